@@ -0,0 +1,166 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync"
+	"text/template"
+)
+
+var (
+	count        int
+	manifestName string
+)
+
+// batchName holds the values available to the output file name template.
+type batchName struct {
+	Index int // The index (1 - Count) of the machine.
+	Count int // The number of machines being generated.
+}
+
+// manifestEntry describes one of the machines written by a batch generation.
+type manifestEntry struct {
+	Index       int    `json:"index"`
+	File        string `json:"file"`
+	Source      string `json:"source"`
+	Fingerprint string `json:"fingerprint"`
+	SHA256      string `json:"sha256"`
+	KAT         string `json:"kat,omitempty"`
+}
+
+func init() {
+	rootCmd.PersistentFlags().IntVarP(&count, "count", "n", 1, `Number of proforma machines to generate.  If more than one machine is
+	generated, the output file is a template for the file names, for example
+	"proforma-{{.Index}}.json", and a manifest of the files is written.`)
+	rootCmd.PersistentFlags().StringVar(&manifestName, "manifest", "-",
+		"file to write the manifest of the machines generated with --count to")
+}
+
+// outputExtensions gives the file name extension used for each output type.
+var outputExtensions = map[string]string{
+	"json": ".json", "ikm": ".go", "c": ".h", "rust": ".rs", "python": ".py"}
+
+// generateBatch generates count machines, writing each one to the file named
+// by the output file name template and the list of files and fingerprints to
+// the manifest.  The machines are generated in parallel if the random source
+// allows it, otherwise they are generated in order so that the machines from a
+// keyed source are reproducible.
+func generateBatch(oType string) error {
+	if count < 1 {
+		return withExitCode(exitUsage, fmt.Errorf("%d is not a valid count", count))
+	}
+	nameTemplate := outputFileName
+	if nameTemplate == "-" || len(nameTemplate) == 0 {
+		nameTemplate = "proforma-{{.Index}}" + outputExtensions[oType]
+	}
+	tmpl, err := template.New("outputfile").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	names := make([]string, count)
+	seen := make(map[string]bool, count)
+	for i := range names {
+		var name bytes.Buffer
+		if err := tmpl.Execute(&name, batchName{Index: i + 1, Count: count}); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		names[i] = name.String()
+		if seen[names[i]] {
+			return withExitCode(exitUsage, fmt.Errorf("the output file template %q does not create unique file names", nameTemplate))
+		}
+		seen[names[i]] = true
+	}
+
+	machines := make([]*Machine, count)
+	errs := make([]error, count)
+	if isConcurrent(source) {
+		var wg sync.WaitGroup
+		next := make(chan int)
+		for range min(runtime.GOMAXPROCS(0), count) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					machines[i], errs[i] = newMachine()
+				}
+			}()
+		}
+		for i := range machines {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	} else {
+		for i := range machines {
+			if machines[i], errs[i] = newMachine(); errs[i] != nil {
+				break
+			}
+		}
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("machine %d: %w", i+1, err)
+		}
+	}
+
+	manifest := make([]manifestEntry, count)
+	for i, m := range machines {
+		outputFile, err := openOutputFile(names[i])
+		if err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("writing machine %d: %w", i+1, err))
+		}
+		err = writeProForma(outputFile, oType, m)
+		if cErr := outputFile.Close(); err == nil {
+			err = cErr
+		}
+		if err == nil && oType == "json" {
+			err = writeMeta(names[i], m)
+		}
+		if err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("writing machine %d: %w", i+1, err))
+		}
+		sum := m.fingerprint()
+		manifest[i] = manifestEntry{
+			Index:       i + 1,
+			File:        names[i],
+			Source:      sourceName,
+			Fingerprint: m.fingerprintText(),
+			SHA256:      hex.EncodeToString(sum[:]),
+		}
+		if katVectors {
+			manifest[i].KAT = katFileName(names[i])
+			if err = writeKat(manifest[i].KAT, m); err != nil {
+				return withExitCode(exitOutput, fmt.Errorf("writing the test vectors for machine %d: %w", i+1, err))
+			}
+		}
+	}
+	manifestFile, err := openOutputFile(manifestName)
+	if err != nil {
+		return withExitCode(exitOutput, fmt.Errorf("writing the manifest: %w", err))
+	}
+	defer manifestFile.Close()
+	jEncoder := json.NewEncoder(manifestFile)
+	jEncoder.SetIndent("", "  ")
+	if err = jEncoder.Encode(manifest); err != nil {
+		return withExitCode(exitOutput, fmt.Errorf("writing the manifest: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	shareThreshold int
+	shareCount     int
+	shareFileName  string
+)
+
+// ceremonySalt is the HKDF salt used to derive the generator key from the
+// master seed.
+const ceremonySalt = "genProforma ceremony v1"
+
+// ceremonyCmd represents the ceremony command
+var ceremonyCmd = &cobra.Command{
+	Use:   "ceremony",
+	Short: "Generate a new proforma machine from a master seed split into shares",
+	Long: `Generate a new proforma machine for a key ceremony.  A random 256 bit master
+seed is created and split into --shares Shamir shares, any --threshold of which
+rebuild it.  The machine is generated from the master seed, which is then
+forgotten; "genProforma recover" rebuilds the identical machine from any
+--threshold of the shares.
+
+Each share is printed with a checksum, so that mistyped shares are caught, and
+with the identifier of the ceremony.  With --share-file each share is written
+to its own file instead, named by a template such as "share-{{.Index}}.txt".`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{sourceAnnotation: "ceremony"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCommand(cmd, args, "json")
+	},
+}
+
+// recoverCmd represents the recover command
+var recoverCmd = &cobra.Command{
+	Use:   "recover [share...]",
+	Short: "Rebuild the proforma machine of a key ceremony from its shares",
+	Long: `Rebuild the proforma machine generated by "genProforma ceremony" from the
+threshold number of its shares.  The shares are given as arguments or, if there
+are none, entered one to a line without being echoed.  The fingerprint of the
+rebuilt machine matches the one shown at the ceremony.`,
+	Annotations: map[string]string{sourceAnnotation: "recover"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCommand(cmd, args, "json")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ceremonyCmd)
+	rootCmd.AddCommand(recoverCmd)
+	ceremonyCmd.Flags().IntVar(&shareThreshold, "threshold", 3, "number of shares needed to rebuild the machine")
+	ceremonyCmd.Flags().IntVar(&shareCount, "shares", 5, "number of shares to create")
+	ceremonyCmd.Flags().StringVar(&shareFileName, "share-file", "", "write each share to a file named by this template")
+	RegisterSource("ceremony", newCeremonySource)
+	RegisterSource("recover", newRecoverSource)
+}
+
+// newCeremonySource creates a master seed, hands out its shares and returns a
+// Source seeded with it.
+func newCeremonySource([]string) (Source, error) {
+	seed := make([]byte, 32)
+	id := make([]byte, 4)
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, err
+	}
+	shares, err := splitSecret(seed, shareThreshold, shareCount, hex.EncodeToString(id), rand.Reader)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	if err = handOutShares(shares); err != nil {
+		return nil, withExitCode(exitOutput, fmt.Errorf("handing out the shares: %w", err))
+	}
+	return seedSource(seed), nil
+}
+
+// handOutShares prints the shares, or writes them to the files named by the
+// --share-file template.
+func handOutShares(shares []share) error {
+	fmt.Fprintf(os.Stderr, "Ceremony %s: %d shares, any %d of which rebuild the machine.\n",
+		shares[0].id, len(shares), shares[0].threshold)
+	if len(shareFileName) == 0 {
+		for _, s := range shares {
+			fmt.Fprintf(os.Stderr, "Share %d of %d: %s\n", s.x, len(shares), s)
+		}
+		return nil
+	}
+	tmpl, err := template.New("sharefile").Option("missingkey=error").Parse(shareFileName)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	seen := make(map[string]bool)
+	for _, s := range shares {
+		var name bytes.Buffer
+		if err := tmpl.Execute(&name, batchName{Index: int(s.x), Count: len(shares)}); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		if seen[name.String()] {
+			return withExitCode(exitUsage, fmt.Errorf("the share file template %q does not create unique file names", shareFileName))
+		}
+		seen[name.String()] = true
+		// The share files must not already exist, so a share is never
+		// silently replaced.
+		f, err := os.OpenFile(name.String(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(f, s)
+		if cErr := f.Close(); err == nil {
+			err = cErr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Share %d of %d written to %s\n", s.x, len(shares), name.String())
+	}
+	return nil
+}
+
+// newRecoverSource returns a Source seeded with the master seed rebuilt from
+// the shares given as arguments, or entered by the operator.
+func newRecoverSource(args []string) (Source, error) {
+	var shares []share
+	for _, arg := range args {
+		s, err := parseShare(arg)
+		if err != nil {
+			return nil, withExitCode(exitSecret, fmt.Errorf("%s: %w", arg, err))
+		}
+		shares = append(shares, s)
+	}
+	if len(args) == 0 {
+		readLine := lineReader()
+		for len(shares) == 0 || len(shares) < shares[0].threshold {
+			if len(shares) == 0 {
+				fmt.Fprint(os.Stderr, "Enter a share: ")
+			} else {
+				fmt.Fprintf(os.Stderr, "Enter a share (%d of %d entered): ", len(shares), shares[0].threshold)
+			}
+			line, err := readLine()
+			if err != nil {
+				return nil, withExitCode(exitSecret, fmt.Errorf("reading a share: %w", err))
+			}
+			s, err := parseShare(line)
+			if err == nil && len(shares) != 0 {
+				if s.id != shares[0].id || s.threshold != shares[0].threshold {
+					err = fmt.Errorf("the share is not from ceremony %s", shares[0].id)
+				} else if slices.ContainsFunc(shares, func(o share) bool { return o.x == s.x }) {
+					err = fmt.Errorf("share %d was already entered", s.x)
+				}
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err, "- enter the share again.")
+				continue
+			}
+			shares = append(shares, s)
+		}
+	}
+	seed, err := combineShares(shares)
+	if err != nil {
+		return nil, withExitCode(exitSecret, err)
+	}
+	fmt.Fprintf(os.Stderr, "Rebuilt the master seed of ceremony %s.\n", shares[0].id)
+	return seedSource(seed), nil
+}
+
+// seedSource returns the Source used to generate the machine from the master
+// seed of a ceremony.
+func seedSource(seed []byte) Source {
+	key := hkdf(seed, []byte(ceremonySalt), []byte("proforma"), sha256.Size)
+	return NewStreamSource(newHmacStream(key).Read)
+}
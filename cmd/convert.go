@@ -0,0 +1,89 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	fromType string
+	toType   string
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert [flags] inputFile [outputFile]",
+	Short: "Convert a proforma machine to a different output type",
+	Long: `Convert reads an existing proforma machine and writes it formatted as a
+different output type.  If the input or output type is not given, it is
+determined from the extension of the file name (.json, .go, .h, .rs or .py).
+If inputFile is a directory, such as an ikmachine checkout, the proformaRotors
+and proformaPermutator definitions are read from the Go files in it.  If
+outputFile is not given, the --outputfile flag is used.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkInputType(); err != nil {
+			return err
+		}
+		m, err := loadProFormaFile(args[0], fromType)
+		if err != nil {
+			return withExitCode(exitInput, err)
+		}
+		name := outputFileName
+		if len(args) == 2 {
+			name = args[1]
+		}
+		oType := toType
+		if len(oType) == 0 {
+			oType = typeFromFileName(name)
+			if len(oType) == 0 {
+				return withExitCode(exitUsage, errors.New("the output type could not be determined, use --to to set it"))
+			}
+		}
+		if !slices.Contains(outputTypes, oType) {
+			return withExitCode(exitUsage, fmt.Errorf("%s is not a valid output type", oType))
+		}
+		outputFile, err := openOutputFile(name)
+		if err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("writing the machine: %w", err))
+		}
+		defer outputFile.Close()
+		if err = writeProForma(outputFile, oType, m); err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("writing the machine: %w", err))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringVar(&fromType, "from", "", `Type of the input file ("json" or "ikm")`)
+	convertCmd.Flags().StringVar(&toType, "to", "", `Type to convert to ("json", "ikm", "c", "rust" or "python")`)
+}
+
+// checkInputType verifies that the input type given by --from, if any, is
+// valid.
+func checkInputType() error {
+	if len(fromType) != 0 && !slices.Contains(inputTypes, fromType) {
+		return withExitCode(exitUsage, fmt.Errorf("%s is not a valid input type", fromType))
+	}
+	return nil
+}
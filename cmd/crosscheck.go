@@ -0,0 +1,146 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/bgallie/tntengine"
+)
+
+// crossCheckBlocks is the number of blocks encrypted and decrypted by the
+// cross-check.
+const crossCheckBlocks = 64
+
+var crossCheck bool
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&crossCheck, "cross-check", false, `Load each generated machine into tntengine and check it before it is
+	written: the rotors must read back the bits they were generated with, the
+	permutators must be invertible, and known plaintext must encrypt to distinct
+	blocks that decrypt back to the plaintext.  The ikmachine proforma is compiled
+	into ikmachine, so it cannot be loaded at run time and is not checked.`)
+}
+
+// tntCryptors loads the rotors and permutators of the machine into tntengine,
+// in the order of the machine's layout.  A tntengine permutator has a single
+// cycle through all of Randp, so the cycles of a permutator are merged into
+// one, as they are when tntengine uses its own proforma permutator.
+func tntCryptors(m *Machine) []tntengine.Cryptor {
+	var res []tntengine.Cryptor
+	for _, c := range m.components() {
+		switch v := c.(type) {
+		case *Rotor:
+			res = append(res, new(tntengine.Rotor).New(int(v.Size), int(v.Start), int(v.Step), v.Rotor))
+		case *Permutator:
+			res = append(res, new(tntengine.Permutator).New(len(v.Randp), v.Randp))
+		}
+	}
+	return res
+}
+
+// rotorBits returns the 256 bits of the rotor starting at bit pos, read from
+// the first Size bits of the rotor so that the slice is not used.
+func rotorBits(r *Rotor, pos int) []byte {
+	res := make([]byte, tntengine.CipherBlockBytes)
+	for i := range tntengine.CipherBlockSize {
+		b := (pos + i) % int(r.Size)
+		if r.Rotor[b>>3]&(1<<(b&7)) != 0 {
+			res[i>>3] |= 1 << (i & 7)
+		}
+	}
+	return res
+}
+
+// engineCheck runs the machine through tntengine, returning all of the
+// problems found.
+func engineCheck(m *Machine) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	var errs []error
+	for i, r := range m.Rotors {
+		tr := new(tntengine.Rotor).New(int(r.Size), int(r.Start), int(r.Step), r.Rotor)
+		if !bytes.Equal(tr.Rotor, r.Rotor) {
+			errs = append(errs, fmt.Errorf("rotor %d: tntengine slices the rotor differently", i+1))
+		}
+		// Adding the rotor to a block of zeros gives the bits of the rotor,
+		// first at its start and then a step further on.
+		for blk := range 2 {
+			pos := (int(r.Start) + blk*int(r.Step)) % int(r.Size)
+			if got := tr.ApplyF(make(tntengine.CipherBlock, tntengine.CipherBlockBytes)); !bytes.Equal(got, rotorBits(r, pos)) {
+				errs = append(errs, fmt.Errorf("rotor %d: block %d does not hold the rotor bits at %d", i+1, blk+1, pos))
+			}
+		}
+	}
+	for i, p := range m.Permutators {
+		enc := new(tntengine.Permutator).New(len(p.Randp), p.Randp)
+		dec := new(tntengine.Permutator).New(len(p.Randp), p.Randp)
+		// Each bit, in each of the states of the permutator, must move to a
+		// single bit that decrypts back to it.
+		for bit := range tntengine.CipherBlockSize {
+			blk := make(tntengine.CipherBlock, tntengine.CipherBlockBytes)
+			tntengine.SetBit(blk, uint(bit))
+			ct := enc.ApplyF(blk)
+			if onesCount(ct) != 1 || !bytes.Equal(dec.ApplyG(ct), blk) {
+				errs = append(errs, fmt.Errorf("permutator %d: bit %d is not permuted invertibly", i+1, bit))
+				break
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+
+	// Encrypt blocks of zeros.  Each must encrypt to a different block and
+	// decrypt back to zeros with a second copy of the machine.
+	enc, dec := tntCryptors(m), tntCryptors(m)
+	plain := make(tntengine.CipherBlock, tntengine.CipherBlockBytes)
+	seen := make(map[string]int)
+	for i := range crossCheckBlocks {
+		ct := append(tntengine.CipherBlock(nil), plain...)
+		for _, c := range enc {
+			ct = c.ApplyF(ct)
+		}
+		if bytes.Equal(ct, plain) {
+			errs = append(errs, fmt.Errorf("block %d is not changed by encryption", i+1))
+		}
+		if j, ok := seen[string(ct)]; ok {
+			errs = append(errs, fmt.Errorf("blocks %d and %d encrypt to the same block", j+1, i+1))
+		}
+		seen[string(ct)] = i
+		pt := append(tntengine.CipherBlock(nil), ct...)
+		for j := len(dec) - 1; j >= 0; j-- {
+			pt = dec[j].ApplyG(pt)
+		}
+		if !bytes.Equal(pt, plain) {
+			errs = append(errs, fmt.Errorf("block %d does not decrypt to the plaintext", i+1))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// onesCount returns the number of bits set in the block.
+func onesCount(blk []byte) int {
+	n := 0
+	for _, b := range blk {
+		n += bits.OnesCount8(b)
+	}
+	return n
+}
@@ -0,0 +1,61 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestEngineCheck checks that generated machines pass the tntengine
+// cross-check and that damaged machines do not.
+func TestEngineCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(m *Machine)
+		want   string // part of the error, empty if the machine passes
+	}{
+		{"generated", func(m *Machine) {}, ""},
+		{"slice", func(m *Machine) {
+			r := m.Rotors[2]
+			r.Rotor[int(r.Size)/8+3] ^= 0x10
+		}, "rotor 3: tntengine slices the rotor differently"},
+		{"randp", func(m *Machine) {
+			m.Permutators[1].Randp[7] = m.Permutators[1].Randp[8]
+		}, "permutator 2: bit"},
+		{"zero rotors", func(m *Machine) {
+			for _, r := range m.Rotors {
+				clear(r.Rotor)
+			}
+		}, "blocks 1 and 2 encrypt to the same block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := generateMachine(NewStreamSource(newHmacStream([]byte("cross-check")).Read), engineLayout, rotorSizes, cycleSizes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.damage(m)
+			err = engineCheck(m)
+			switch {
+			case len(tt.want) == 0 && err != nil:
+				t.Errorf("engineCheck() = %v, want nil", err)
+			case len(tt.want) != 0 && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("engineCheck() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
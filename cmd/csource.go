@@ -0,0 +1,91 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// cSource formats the rotors and permutators as a C header file.  The structs
+// defined in the header use the same field names and sizes as the Rotor, Cycle
+// and Permutator types used in IkMachine.  It is an error for the rotors or
+// the permutators to differ in size, as the header declares them as arrays.
+func cSource(m *Machine) (string, error) {
+	if err := m.checkFixedSizes(); err != nil {
+		return "", err
+	}
+	var output bytes.Buffer
+	rotors, permutators := m.Rotors, m.Permutators
+	output.WriteString("// Code generated by genProforma. DO NOT EDIT.\n")
+	output.WriteString(m.fingerprintComment("// ") + "\n")
+	output.WriteString("#ifndef GENPROFORMA_PROFORMA_H\n#define GENPROFORMA_PROFORMA_H\n\n")
+	output.WriteString("#include <stdint.h>\n\n")
+	output.WriteString(fmt.Sprintf("#define PROFORMA_ROTOR_COUNT      %d\n", len(rotors)))
+	output.WriteString(fmt.Sprintf("#define PROFORMA_ROTOR_BYTES      %d\n", len(rotors[0].Rotor)))
+	output.WriteString(fmt.Sprintf("#define PROFORMA_PERMUTATOR_COUNT %d\n", len(permutators)))
+	output.WriteString(fmt.Sprintf("#define PROFORMA_CYCLE_COUNT      %d\n\n", len(permutators[0].Cycles)))
+	output.WriteString("typedef struct {\n" +
+		"\tint16_t size;    /* the size in bits for this rotor */\n" +
+		"\tint16_t start;   /* the initial starting position of the rotor */\n" +
+		"\tint16_t step;    /* the step size in bits for this rotor */\n" +
+		"\tint16_t current; /* the current position of this rotor */\n" +
+		"\tuint8_t rotor[PROFORMA_ROTOR_BYTES];\n" +
+		"} Rotor;\n\n")
+	output.WriteString("typedef struct {\n" +
+		"\tint16_t start;   /* The starting point (into randp) for this cycle. */\n" +
+		"\tint16_t length;  /* The length of the cycle. */\n" +
+		"\tint16_t current; /* The point in the cycle [0 .. cycles.length-1] to start */\n" +
+		"} Cycle;\n\n")
+	output.WriteString("typedef struct {\n" +
+		"\tint32_t currentState;  /* Current number of cycles for this permutator. */\n" +
+		"\tint32_t maximalStates; /* Maximum number of cycles before repeating. */\n" +
+		"\tCycle   cycles[PROFORMA_CYCLE_COUNT];\n" +
+		"\tuint8_t randp[256];    /* Values 0 - 255 in a random order. */\n" +
+		"\tuint8_t bitPerm[256];  /* Permutation table created from randp. */\n" +
+		"} Permutator;\n\n")
+	output.WriteString("static const Rotor proformaRotors[PROFORMA_ROTOR_COUNT] = {\n")
+	for _, r := range rotors {
+		output.WriteString("\t{\n")
+		output.WriteString(fmt.Sprintf("\t\t.size    = %d,\n", r.Size))
+		output.WriteString(fmt.Sprintf("\t\t.start   = %d,\n", r.Start))
+		output.WriteString(fmt.Sprintf("\t\t.step    = %d,\n", r.Step))
+		output.WriteString(fmt.Sprintf("\t\t.current = %d,\n", r.Current))
+		output.WriteString("\t\t.rotor   = {\n")
+		writeHexBytes(&output, "\t\t\t", r.Rotor)
+		output.WriteString("\t\t},\n\t},\n")
+	}
+	output.WriteString("};\n\n")
+	output.WriteString("static const Permutator proformaPermutator[PROFORMA_PERMUTATOR_COUNT] = {\n")
+	for _, p := range permutators {
+		output.WriteString("\t{\n")
+		output.WriteString(fmt.Sprintf("\t\t.currentState  = %d,\n", p.CurrentState))
+		output.WriteString(fmt.Sprintf("\t\t.maximalStates = %d,\n", p.MaximalStates))
+		output.WriteString("\t\t.cycles = {\n")
+		for _, c := range p.Cycles {
+			output.WriteString(fmt.Sprintf("\t\t\t{.start = %d, .length = %d, .current = %d},\n",
+				c.Start, c.Length, c.Current))
+		}
+		output.WriteString("\t\t},\n\t\t.randp = {\n")
+		writeHexBytes(&output, "\t\t\t", p.Randp)
+		output.WriteString("\t\t},\n\t\t.bitPerm = {\n")
+		writeHexBytes(&output, "\t\t\t", p.bitPerm[:])
+		output.WriteString("\t\t},\n\t},\n")
+	}
+	output.WriteString("};\n\n")
+	output.WriteString("#endif /* GENPROFORMA_PROFORMA_H */\n")
+	return output.String(), nil
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	dieType  string
+	diceBits int
+)
+
+// diceSalt is the HMAC key used to create the seed from the dice rolls.
+const diceSalt = "genProforma dice v1"
+
+// dieSides gives the number of sides for each of the supported die types.
+var dieSides = map[string]int{"coin": 2, "d6": 6, "d20": 20}
+
+// diceCmd represents the dice command
+var diceCmd = &cobra.Command{
+	Use:   "dice",
+	Short: "Generate a new proforma machine from dice rolls or coin flips",
+	Long: `Generate a new proforma machine from physical dice rolls or coin flips entered
+by the operator, for use in air-gapped key ceremonies.  The rolls are entered
+without being echoed, several to a line separated by spaces.  Dice rolls are
+entered as numbers (1-6 or 1-20) and coin flips as h or t.
+
+To remove any bias of the dice, the rolls are compared in pairs: a pair with
+the first roll lower gives a 0 bit, a pair with the first roll higher gives a
+1 bit and equal rolls are discarded.  Rolls are requested until the number of
+bits given by --bits is collected, then the bits and the rolls are hashed
+into the seed of a HMAC-SHA256 based generator.`,
+	Annotations: map[string]string{sourceAnnotation: "dice"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCommand(cmd, args, "json")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diceCmd)
+	diceCmd.Flags().StringVar(&dieType, "die", "d6", `Type of die used ("d6", "d20" or "coin")`)
+	diceCmd.Flags().IntVar(&diceBits, "bits", 128, "number of unbiased bits to collect")
+	RegisterSource("dice", newDiceSource)
+}
+
+// diceCollector debiases the rolls entered by the operator and keeps track
+// of the number of bits collected.
+type diceCollector struct {
+	sides   int
+	rolls   []byte // every roll entered
+	bits    []byte // the unbiased bits, one per byte
+	pending int    // the first roll of the current pair, 0 if there is none
+}
+
+// add adds a roll (1 - sides) to the collector.
+func (c *diceCollector) add(roll int) {
+	c.rolls = append(c.rolls, byte(roll))
+	if c.pending == 0 {
+		c.pending = roll
+		return
+	}
+	if c.pending < roll {
+		c.bits = append(c.bits, 0)
+	} else if c.pending > roll {
+		c.bits = append(c.bits, 1)
+	}
+	c.pending = 0
+}
+
+// parse converts a single roll entered by the operator into its value.
+func (c *diceCollector) parse(field string) (int, error) {
+	if c.sides == 2 {
+		switch strings.ToLower(field) {
+		case "h", "heads":
+			return 1, nil
+		case "t", "tails":
+			return 2, nil
+		}
+		return 0, fmt.Errorf("%q is not a coin flip (h or t)", field)
+	}
+	roll, err := strconv.Atoi(field)
+	if err != nil || roll < 1 || roll > c.sides {
+		return 0, fmt.Errorf("%q is not a roll between 1 and %d", field, c.sides)
+	}
+	return roll, nil
+}
+
+// addLine adds the rolls of a line entered by the operator.  Every roll is
+// checked before any is added, so a line with a bad roll adds nothing and can
+// be entered again.
+func (c *diceCollector) addLine(line string) error {
+	var rolls []int
+	for _, field := range strings.Fields(line) {
+		roll, err := c.parse(field)
+		if err != nil {
+			return err
+		}
+		rolls = append(rolls, roll)
+	}
+	for _, roll := range rolls {
+		c.add(roll)
+	}
+	return nil
+}
+
+// seed returns the seed made from the collected bits and rolls.
+func (c *diceCollector) seed() []byte {
+	extract := hmac.New(sha256.New, []byte(diceSalt))
+	mixInput(extract, "bits", c.bits)
+	mixInput(extract, "rolls", c.rolls)
+	return extract.Sum(nil)
+}
+
+// newDiceSource returns a Source seeded with the dice rolls entered by the
+// operator.
+func newDiceSource([]string) (Source, error) {
+	sides, ok := dieSides[dieType]
+	if !ok {
+		return nil, withExitCode(exitUsage, fmt.Errorf("%s is not a valid die type", dieType))
+	}
+	if diceBits < 1 {
+		return nil, withExitCode(exitUsage, fmt.Errorf("%d is not a valid number of bits", diceBits))
+	}
+	readLine := lineReader()
+	c := &diceCollector{sides: sides}
+	what := "rolls"
+	if sides == 2 {
+		what = "flips"
+	}
+	for len(c.bits) < diceBits {
+		fmt.Fprintf(os.Stderr, "Enter %s %s (%d of %d bits collected): ",
+			dieType, what, len(c.bits), diceBits)
+		line, err := readLine()
+		if err != nil {
+			return nil, fmt.Errorf("only %d of %d bits were collected: %w", len(c.bits), diceBits, err)
+		}
+		if err = c.addLine(line); err != nil {
+			fmt.Fprintln(os.Stderr, err, "- the line was ignored, enter all of it again.")
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Collected %d bits from %d rolls.\n", len(c.bits), len(c.rolls))
+	return NewStreamSource(newHmacStream(c.seed()).Read), nil
+}
+
+// lineReader returns a function that reads a line from stdin.  If stdin is a
+// terminal the line is not echoed.
+func lineReader() func() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		return func() (string, error) {
+			line, err := term.ReadPassword(fd)
+			fmt.Fprintln(os.Stderr, "")
+			return string(line), err
+		}
+	}
+	r := stdinReader()
+	return func() (string, error) {
+		line, err := readLine(r)
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("end of input")
+		}
+		return line, err
+	}
+}
+
+var (
+	// stdinBuf buffers stdin for everything that reads lines from it, and
+	// stdinFile is the file it was made for.
+	stdinBuf  *bufio.Reader
+	stdinFile *os.File
+)
+
+// stdinReader returns the buffered reader of stdin shared by the wizard and
+// the sources that read lines from stdin, so that what one of them reads
+// ahead is not lost to the others.
+func stdinReader() *bufio.Reader {
+	if stdinBuf == nil || stdinFile != os.Stdin {
+		stdinBuf, stdinFile = bufio.NewReader(os.Stdin), os.Stdin
+	}
+	return stdinBuf
+}
+
+// readLine reads a line from r without the line ending.  The last line does
+// not need a line ending; io.EOF is returned only if there is no line left.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if errors.Is(err, io.EOF) && len(line) != 0 {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestDiceDebias checks that the rolls are compared in pairs, across lines,
+// with equal pairs discarded.
+func TestDiceDebias(t *testing.T) {
+	c := &diceCollector{sides: 6}
+	for _, line := range []string{"1 2 6 3", "4 4 5", "", "2 3 1"} {
+		if err := c.addLine(line); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+	}
+	// The pairs are (1,2) (6,3) (4,4) (5,2) (3,1).
+	if want := []byte{0, 1, 1, 1}; !bytes.Equal(c.bits, want) {
+		t.Errorf("bits = %v, want %v", c.bits, want)
+	}
+	if want := []byte{1, 2, 6, 3, 4, 4, 5, 2, 3, 1}; !bytes.Equal(c.rolls, want) {
+		t.Errorf("rolls = %v, want %v", c.rolls, want)
+	}
+	if c.pending != 0 {
+		t.Errorf("pending = %d after an even number of rolls", c.pending)
+	}
+
+	coin := &diceCollector{sides: 2}
+	if err := coin.addLine("h t T heads t h h h"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0, 1, 1}; !bytes.Equal(coin.bits, want) {
+		t.Errorf("coin bits = %v, want %v", coin.bits, want)
+	}
+}
+
+// TestDiceBadLine checks that a line with a bad roll adds none of its rolls,
+// so that entering it again does not count the good rolls twice.
+func TestDiceBadLine(t *testing.T) {
+	c := &diceCollector{sides: 6}
+	if err := c.addLine("3"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"1 2 7", "5 x", "0 1", "2 h"} {
+		if err := c.addLine(line); err == nil {
+			t.Errorf("the line %q was accepted", line)
+		}
+	}
+	if len(c.rolls) != 1 || len(c.bits) != 0 || c.pending != 3 {
+		t.Errorf("the bad lines changed the collector: rolls %v, bits %v, pending %d", c.rolls, c.bits, c.pending)
+	}
+	if err := new(diceCollector).addLine(""); err != nil {
+		t.Errorf("an empty line was rejected: %v", err)
+	}
+	d20 := &diceCollector{sides: 20}
+	if err := d20.addLine("20 1"); err != nil || !bytes.Equal(d20.bits, []byte{1}) {
+		t.Errorf("d20 rolls: bits %v, %v", d20.bits, err)
+	}
+	if err := d20.addLine("21"); err == nil {
+		t.Errorf("21 was accepted as a d20 roll")
+	}
+	if err := (&diceCollector{sides: 2}).addLine("h x"); err == nil {
+		t.Errorf("x was accepted as a coin flip")
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [flags] file1 file2",
+	Short: "Compare two proforma machines",
+	Long: `Compare two proforma machines component by component, reporting the
+differences in the rotor sizes, start and step values, the Hamming distance
+between the rotor bits and the distance between the permutators' randp
+permutations (Kendall tau distance and number of fixed points).
+
+The exit status is 0 if the machines are identical and 8 if they differ.  If
+the command line is not valid the exit status is 2, and if a machine could not
+be read it is 6.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkInputType(); err != nil {
+			return err
+		}
+		var machines [2]*Machine
+		for i, name := range args {
+			m, err := loadProFormaFile(name, fromType)
+			if err != nil {
+				return withExitCode(exitInput, err)
+			}
+			machines[i] = m
+		}
+		if diffMachines(cmd.OutOrStdout(), machines[0], machines[1]) {
+			return errMachinesDiffer
+		}
+		return nil
+	},
+}
+
+// errMachinesDiffer is returned by the diff command when the machines differ.
+// The differences are the output, so the error itself is not reported.
+var errMachinesDiffer = &codedError{code: exitDiffer, err: errors.New("the machines differ"), silent: true}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVar(&fromType, "from", "", `Type of the input files ("json" or "ikm")`)
+}
+
+// diffMachines writes the differences between machines a and b to w,
+// returning true if they differ.
+func diffMachines(w io.Writer, a, b *Machine) bool {
+	differ := false
+	if a.Layout != b.Layout {
+		fmt.Fprintf(w, "layout: %s -> %s\n", a.Layout, b.Layout)
+		differ = true
+	}
+	aComponents, bComponents := a.components(), b.components()
+	for i := range max(len(aComponents), len(bComponents)) {
+		if i >= len(aComponents) || i >= len(bComponents) {
+			fmt.Fprintf(w, "component %d: only in one machine\n", i+1)
+			differ = true
+			continue
+		}
+		var output bytes.Buffer
+		switch ac := aComponents[i].(type) {
+		case *Rotor:
+			if bc, ok := bComponents[i].(*Rotor); ok {
+				diffRotors(&output, ac, bc)
+			} else {
+				output.WriteString("\ttype:    rotor -> permutator\n")
+			}
+		case *Permutator:
+			if bc, ok := bComponents[i].(*Permutator); ok {
+				diffPermutators(&output, ac, bc)
+			} else {
+				output.WriteString("\ttype:    permutator -> rotor\n")
+			}
+		}
+		if output.Len() == 0 {
+			fmt.Fprintf(w, "component %d: identical\n", i+1)
+		} else {
+			fmt.Fprintf(w, "component %d:\n%s", i+1, output.String())
+			differ = true
+		}
+	}
+	return differ
+}
+
+// diffRotors writes the differences between rotors a and b to output.
+func diffRotors(output *bytes.Buffer, a, b *Rotor) {
+	if a.Size != b.Size {
+		output.WriteString(fmt.Sprintf("\tsize:    %d -> %d\n", a.Size, b.Size))
+	}
+	if a.Start != b.Start {
+		output.WriteString(fmt.Sprintf("\tstart:   %d -> %d\n", a.Start, b.Start))
+	}
+	if a.Step != b.Step {
+		output.WriteString(fmt.Sprintf("\tstep:    %d -> %d\n", a.Step, b.Step))
+	}
+	if a.Current != b.Current {
+		output.WriteString(fmt.Sprintf("\tcurrent: %d -> %d\n", a.Current, b.Current))
+	}
+	// Compare the bits that are in both rotors (not including the slice).
+	nBits := int(min(a.Size, b.Size))
+	if dist := hammingDistance(a.Rotor, b.Rotor, nBits); dist != 0 {
+		output.WriteString(fmt.Sprintf("\tbits:    Hamming distance %d of %d bits (%.2f%%)\n",
+			dist, nBits, 100*float64(dist)/float64(nBits)))
+	}
+}
+
+// diffPermutators writes the differences between permutators a and b to output.
+func diffPermutators(output *bytes.Buffer, a, b *Permutator) {
+	if a.CurrentState != b.CurrentState {
+		output.WriteString(fmt.Sprintf("\tcurrentState:  %d -> %d\n", a.CurrentState, b.CurrentState))
+	}
+	if a.MaximalStates != b.MaximalStates {
+		output.WriteString(fmt.Sprintf("\tmaximalStates: %d -> %d\n", a.MaximalStates, b.MaximalStates))
+	}
+	if !slices.Equal(a.Cycles, b.Cycles) {
+		output.WriteString(fmt.Sprintf("\tcycles:        %v -> %v\n", a.Cycles, b.Cycles))
+	}
+	if !bytes.Equal(a.Randp, b.Randp) {
+		output.WriteString(fmt.Sprintf("\trandp:         Kendall tau distance %d of %d, %d fixed points\n",
+			kendallTau(a.Randp, b.Randp), len(a.Randp)*(len(a.Randp)-1)/2, fixedPoints(a.Randp, b.Randp)))
+	}
+}
+
+// hammingDistance returns the number of bits that differ in the first nBits
+// bits of a and b.
+func hammingDistance(a, b []byte, nBits int) int {
+	dist := 0
+	for i := 0; i < nBits/8; i++ {
+		dist += bits.OnesCount8(a[i] ^ b[i])
+	}
+	if rem := nBits & 7; rem != 0 {
+		// The rotor bits are stored least significant bit first.
+		dist += bits.OnesCount8((a[nBits/8] ^ b[nBits/8]) & (0xff >> (8 - rem)))
+	}
+	return dist
+}
+
+// kendallTau returns the Kendall tau distance between the permutations a and
+// b, the number of pairs of values that are in a different order in a and b.
+func kendallTau(a, b []byte) int {
+	var pos [256]int
+	for i, v := range b {
+		pos[v] = i
+	}
+	dist := 0
+	for i := range a {
+		for j := i + 1; j < len(a); j++ {
+			if pos[a[i]] > pos[a[j]] {
+				dist++
+			}
+		}
+	}
+	return dist
+}
+
+// fixedPoints returns the number of positions where a and b have the same value.
+func fixedPoints(a, b []byte) int {
+	cnt := 0
+	for i := range min(len(a), len(b)) {
+		if a[i] == b[i] {
+			cnt++
+		}
+	}
+	return cnt
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// The exit codes of genProforma, listed in the help of the root command so
+// that scripts can tell the failures apart.
+const (
+	exitOK      = 0
+	exitError   = 1 // any error without a more specific code
+	exitUsage   = 2 // the command line is not valid
+	exitSecret  = 3 // the passphrase or the shares
+	exitEntropy = 4 // the source of random data
+	exitOutput  = 5 // writing the results
+	exitInput   = 6 // reading a proforma machine
+	exitCheck   = 7 // the tntengine cross-check
+	exitDiffer  = 8 // diff found differences between the machines
+)
+
+// exitKinds names the exit codes in the --json-errors output.
+var exitKinds = map[int]string{
+	exitError:   "error",
+	exitUsage:   "usage",
+	exitSecret:  "secret",
+	exitEntropy: "entropy",
+	exitOutput:  "output",
+	exitInput:   "input",
+	exitCheck:   "check",
+	exitDiffer:  "differ",
+}
+
+var (
+	jsonErrors bool
+	// commandStarted is set once the command line is parsed and the command
+	// is run, so that errors found before then are reported as usage errors.
+	commandStarted bool
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&jsonErrors, "json-errors", false,
+		"write errors to stderr as a line of JSON with the error, its kind and the exit code")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
+}
+
+// codedError is an error with the exit code it is reported with.  A silent
+// error only sets the exit code, unless it is written with --json-errors.
+type codedError struct {
+	code   int
+	err    error
+	silent bool
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+
+func (e *codedError) Unwrap() error { return e.err }
+
+// withExitCode gives err the exit code code.  An error that already has an
+// exit code keeps it, so the code is set by the failure closest to the cause
+// however much context is added on the way up.
+func withExitCode(code int, err error) error {
+	var ce *codedError
+	if err == nil || errors.As(err, &ce) {
+		return err
+	}
+	return &codedError{code: code, err: err}
+}
+
+// exitCode returns the exit code for err.
+func exitCode(err error) int {
+	var ce *codedError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	return exitError
+}
+
+// errorReport is an error as written with --json-errors.
+type errorReport struct {
+	Error   string `json:"error"`
+	Kind    string `json:"kind"`
+	Code    int    `json:"code"`
+	Command string `json:"command"`
+}
+
+// run runs genProforma with the command line args, writing any error to w,
+// and returns the exit code.
+func run(args []string, w io.Writer) int {
+	commandStarted = false
+	rootCmd.SetArgs(args)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return exitOK
+	}
+	if !commandStarted {
+		err = withExitCode(exitUsage, err)
+	}
+	// If the command line could not be parsed, --json-errors may not have
+	// been reached.
+	reportError(w, cmd, err, jsonErrors || jsonErrorsArg(args))
+	return exitCode(err)
+}
+
+// jsonErrorsArg reports whether --json-errors is given in args.
+func jsonErrorsArg(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--json-errors" || arg == "--json-errors=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// reportError writes the error from cmd to w, as text or as a line of JSON.
+func reportError(w io.Writer, cmd *cobra.Command, err error, asJSON bool) {
+	code := exitCode(err)
+	var ce *codedError
+	if !asJSON && errors.As(err, &ce) && ce.silent {
+		return
+	}
+	if asJSON {
+		res, _ := json.Marshal(errorReport{
+			Error:   err.Error(),
+			Kind:    exitKinds[code],
+			Code:    code,
+			Command: cmd.CommandPath(),
+		})
+		fmt.Fprintf(w, "%s\n", res)
+		return
+	}
+	fmt.Fprintln(w, "Error:", err)
+	if code == exitUsage {
+		fmt.Fprintf(w, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExitCodes runs commands that fail in each of the documented ways and
+// checks the exit code and the error written for them.
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	short := filepath.Join(dir, "short.bin")
+	if err := os.WriteFile(short, make([]byte, 100), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`[{"Size": 5}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "proforma.json")
+	unwritable := filepath.Join(dir, "missing", "proforma.json")
+
+	// The passphrase must not be read from the terminal or the environment.
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	f, err := os.Open(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	os.Stdin = f
+	t.Setenv("GPF_SECRET", "")
+	// A bad flag value is left in the flag.
+	defer func(n int) { count = n }(count)
+	defer func(kat bool) { katVectors = kat }(katVectors)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		msg  string
+	}{
+		{"ok", []string{"random", "-t", "json", "-f", out}, exitOK, ""},
+		{"unknown command", []string{"no-such-command"}, exitUsage, "unknown command"},
+		{"unknown flag", []string{"random", "--no-such-flag"}, exitUsage, "unknown flag"},
+		{"arguments", []string{"file", "-t", "json"}, exitUsage, "accepts 1 arg(s)"},
+		{"output type", []string{"random", "-t", "xml", "-f", out}, exitUsage, "xml is not a valid output type"},
+		{"passphrase", []string{"tntengine", "-t", "json", "-f", out}, exitSecret, "you must supply a password"},
+		{"share", []string{"recover", "-t", "json", "-f", out, "not-a-share"}, exitSecret, "not-a-share"},
+		{"entropy", []string{"file", "-t", "json", "-f", out, short}, exitEntropy, "reading entropy for rotor 1"},
+		{"output", []string{"random", "-t", "json", "-f", unwritable}, exitOutput, "writing the machine"},
+		{"input", []string{"convert", bad, out}, exitInput, bad},
+		{"diff input", []string{"diff", bad, bad}, exitInput, bad},
+		{"unknown source", []string{"generate", "no-such-source"}, exitUsage, "the sources are: ceremony, dice"},
+		{"kat for ikmachine", []string{"random", "-t", "ikm", "--kat", "-f", out}, exitUsage, "tntengine"},
+		// Last, as it sets the count to 0 until it is restored.
+		{"bad flag value", []string{"random", "--count", "many"}, exitUsage, "invalid argument"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			jsonErrors = false
+			if code := run(tc.args, &stderr); code != tc.code {
+				t.Fatalf("exit code = %d, want %d (%s)", code, tc.code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.msg) {
+				t.Errorf("the error %q does not mention %q", stderr.String(), tc.msg)
+			}
+		})
+	}
+}
+
+// TestJSONErrors checks the errors written with --json-errors, including an
+// error found before the flag itself is parsed.
+func TestJSONErrors(t *testing.T) {
+	t.Setenv("GPF_SECRET", "")
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	os.Stdin = f
+	out := filepath.Join(t.TempDir(), "proforma.json")
+	for _, tc := range []struct {
+		args []string
+		want errorReport
+	}{
+		{
+			[]string{"--json-errors", "tntengine", "-t", "json", "-f", out},
+			errorReport{"you must supply a password", "secret", exitSecret, "genProforma tntengine"},
+		},
+		{
+			[]string{"random", "--no-such-flag", "--json-errors"},
+			errorReport{"unknown flag: --no-such-flag", "usage", exitUsage, "genProforma random"},
+		},
+	} {
+		var stderr bytes.Buffer
+		jsonErrors = false
+		code := run(tc.args, &stderr)
+		var got errorReport
+		if err := json.Unmarshal(stderr.Bytes(), &got); err != nil {
+			t.Fatalf("%v: %q is not JSON: %v", tc.args, stderr.String(), err)
+		}
+		if strings.Count(stderr.String(), "\n") != 1 {
+			t.Errorf("%v: the error is not a single line: %q", tc.args, stderr.String())
+		}
+		if !strings.Contains(got.Error, tc.want.Error) || got.Kind != tc.want.Kind ||
+			got.Code != tc.want.Code || got.Command != tc.want.Command {
+			t.Errorf("%v: got %+v, want %+v", tc.args, got, tc.want)
+		}
+		if code != tc.want.Code {
+			t.Errorf("%v: exit code = %d, want %d", tc.args, code, tc.want.Code)
+		}
+	}
+	jsonErrors = false
+}
+
+// TestWithExitCode checks that the exit code closest to the cause is kept.
+func TestWithExitCode(t *testing.T) {
+	err := withExitCode(exitSecret, os.ErrNotExist)
+	err = withExitCode(exitEntropy, fmt.Errorf("starting the source: %w", err))
+	if code := exitCode(err); code != exitSecret {
+		t.Errorf("exit code = %d, want %d", code, exitSecret)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the cause of the error was lost")
+	}
+	if code := exitCode(os.ErrNotExist); code != exitError {
+		t.Errorf("exit code = %d, want %d", code, exitError)
+	}
+	if withExitCode(exitOutput, nil) != nil {
+		t.Errorf("a nil error was given an exit code")
+	}
+}
+
+// TestDiffExitCodes checks that diff exits with its own code when the
+// machines differ, without reporting an error unless --json-errors is given.
+func TestDiffExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i, s := range []Source{goldenSource(), goldenSource(), NewStreamSource(newHmacStream([]byte("diff")).Read)} {
+		m, err := generateMachine(s, engineLayout, rotorSizes, cycleSizes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(dir, fmt.Sprintf("m%d.json", i))
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writeProForma(f, "json", m)
+		if cErr := f.Close(); err == nil {
+			err = cErr
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+	defer func() { jsonErrors = false }()
+
+	for _, tc := range []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{[]string{"diff", names[0], names[1]}, exitOK, ""},
+		{[]string{"diff", names[0], names[2]}, exitDiffer, ""},
+		{[]string{"diff", "--json-errors", names[0], names[2]}, exitDiffer, `"kind":"differ","code":8`},
+	} {
+		var stderr bytes.Buffer
+		stdout.Reset()
+		jsonErrors = false
+		if code := run(tc.args, &stderr); code != tc.code {
+			t.Errorf("%v: exit code = %d, want %d", tc.args, code, tc.code)
+		}
+		if !strings.Contains(stderr.String(), tc.stderr) || (len(tc.stderr) == 0 && stderr.Len() != 0) {
+			t.Errorf("%v: stderr = %q, want %q", tc.args, stderr.String(), tc.stderr)
+		}
+		if differ := strings.Contains(stdout.String(), "differ") || strings.Contains(stdout.String(), "->"); differ != (tc.code == exitDiffer) {
+			t.Errorf("%v: the differences were not written:\n%s", tc.args, stdout.String())
+		}
+	}
+}
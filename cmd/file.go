@@ -0,0 +1,195 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	healthTests bool
+	minEntropy  float64
+)
+
+// fileCmd represents the file command
+var fileCmd = &cobra.Command{
+	Use:   "file [flags] entropyFile",
+	Short: "Generate a new proforma machine from a file of random data",
+	Long: `Generate a new proforma machine using the random data read from a file or
+device, such as a hardware random number generator dump or /dev/hwrng.  The
+data is used directly, with rejection sampling to choose the rotor and
+permutator values, so the file must contain full entropy random data.  It is
+an error for the file to run out of data before the machine is generated.
+
+With --health-tests, the data is checked with the repetition count and
+adaptive proportion tests from NIST SP 800-90B, using the min-entropy per byte
+given by --min-entropy.  The first 1024 bytes are tested and discarded before
+any data is used, and the rest is tested as it is used.`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{sourceAnnotation: "file"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCommand(cmd, args, "json")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fileCmd)
+	fileCmd.Flags().BoolVar(&healthTests, "health-tests", false, "run the SP 800-90B health tests on the data")
+	fileCmd.Flags().Float64Var(&minEntropy, "min-entropy", 8, "assumed min-entropy per byte (0-8] used by the health tests")
+	RegisterSource("file", newFileSource)
+}
+
+// newFileSource returns a Source that reads the random data from the file
+// named by the only argument.
+func newFileSource(args []string) (Source, error) {
+	if len(args) != 1 {
+		return nil, errors.New("the file source needs the name of an entropy file")
+	}
+	if healthTests && (minEntropy <= 0 || minEntropy > 8) {
+		return nil, withExitCode(exitUsage, fmt.Errorf("%g is not a valid min-entropy", minEntropy))
+	}
+	name := args[0]
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r := &entropyReader{name: name, r: bufio.NewReader(f)}
+	if healthTests {
+		r.health = newHealthTester(minEntropy)
+		// Startup testing: the first 1024 samples are tested and discarded.
+		if _, err = r.Read(make([]byte, 1024)); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return fileSource{Source: NewStreamSource(r.Read), Closer: f}, nil
+}
+
+// fileSource is the Source of the file command.  It keeps the entropy file
+// open until it is closed.
+type fileSource struct {
+	Source
+	io.Closer
+}
+
+// entropyReader reads random data from a file, running the health tests on
+// the data if they are enabled.
+type entropyReader struct {
+	name   string
+	r      io.Reader
+	read   int64 // the number of bytes read so far
+	health *healthTester
+}
+
+// Read fills p with data from the file.  It is an error if the file does not
+// have enough data to fill p.
+func (e *entropyReader) Read(p []byte) (int, error) {
+	n, err := io.ReadFull(e.r, p)
+	e.read += int64(n)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, fmt.Errorf("%s ran out of random data after %d bytes", e.name, e.read)
+	} else if err != nil {
+		return n, err
+	}
+	if e.health != nil {
+		for i, b := range p {
+			if err := e.health.test(b); err != nil {
+				return n, fmt.Errorf("%s: byte %d: %w", e.name, e.read-int64(n)+int64(i), err)
+			}
+		}
+	}
+	return n, nil
+}
+
+// aptWindow is the window size used by the adaptive proportion test for
+// non-binary sources.
+const aptWindow = 512
+
+// healthTester implements the repetition count test (SP 800-90B 4.4.1) and
+// the adaptive proportion test (SP 800-90B 4.4.2) on byte samples, with a
+// false positive probability of 2^-20.
+type healthTester struct {
+	rctCutoff int
+	rctLast   byte
+	rctCount  int
+	aptCutoff int
+	aptFirst  byte
+	aptCount  int
+	aptSeen   int
+}
+
+// newHealthTester returns a healthTester for a source with the given
+// min-entropy per byte.
+func newHealthTester(h float64) *healthTester {
+	const alphaBits = 20
+	return &healthTester{
+		rctCutoff: 1 + int(math.Ceil(alphaBits/h)),
+		aptCutoff: 1 + critBinom(aptWindow, math.Exp2(-h), 1-math.Exp2(-alphaBits)),
+	}
+}
+
+// test adds the sample b to the tests, returning an error if either test
+// fails.
+func (t *healthTester) test(b byte) error {
+	if t.rctCount != 0 && b == t.rctLast {
+		t.rctCount++
+		if t.rctCount >= t.rctCutoff {
+			return fmt.Errorf("repetition count test failed: %#02x repeated %d times", b, t.rctCount)
+		}
+	} else {
+		t.rctLast = b
+		t.rctCount = 1
+	}
+
+	if t.aptSeen == 0 {
+		t.aptFirst = b
+		t.aptCount = 1
+	} else if b == t.aptFirst {
+		t.aptCount++
+		if t.aptCount >= t.aptCutoff {
+			return fmt.Errorf("adaptive proportion test failed: %#02x seen %d times in %d samples",
+				b, t.aptCount, t.aptSeen+1)
+		}
+	}
+	t.aptSeen++
+	if t.aptSeen == aptWindow {
+		t.aptSeen = 0
+	}
+	return nil
+}
+
+// critBinom returns the smallest k such that the probability of k or fewer
+// successes in n trials, each with probability p, is at least q.
+func critBinom(n int, p, q float64) int {
+	cdf := 0.0
+	for k := 0; k < n; k++ {
+		lg, _ := math.Lgamma(float64(n + 1))
+		lk, _ := math.Lgamma(float64(k + 1))
+		lnk, _ := math.Lgamma(float64(n - k + 1))
+		cdf += math.Exp(lg - lk - lnk + float64(k)*math.Log(p) + float64(n-k)*math.Log1p(-p))
+		if cdf >= q {
+			return k
+		}
+	}
+	return n
+}
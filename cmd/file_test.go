@@ -0,0 +1,117 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHealthCutoffs checks the cutoffs of the health tests against the
+// values given by SP 800-90B (table 2 for the adaptive proportion test).
+func TestHealthCutoffs(t *testing.T) {
+	tests := []struct {
+		h        float64
+		rct, apt int
+	}{
+		{8, 4, 13},
+		{4, 6, 62},
+		{2, 11, 177},
+		{1, 21, 311},
+	}
+	for _, tc := range tests {
+		ht := newHealthTester(tc.h)
+		if ht.rctCutoff != tc.rct || ht.aptCutoff != tc.apt {
+			t.Errorf("H=%g: cutoffs = %d, %d, want %d, %d", tc.h, ht.rctCutoff, ht.aptCutoff, tc.rct, tc.apt)
+		}
+	}
+}
+
+// runHealth feeds the samples to a new healthTester for H=8, returning the
+// index of the sample that failed and the error, or -1 if none failed.
+func runHealth(samples []byte) (int, error) {
+	ht := newHealthTester(8)
+	for i, b := range samples {
+		if err := ht.test(b); err != nil {
+			return i, err
+		}
+	}
+	return -1, nil
+}
+
+func TestHealthFailures(t *testing.T) {
+	data := make([]byte, 1<<16)
+	newHmacStream([]byte("health")).Read(data)
+	if i, err := runHealth(data); err != nil {
+		t.Errorf("random data failed at byte %d: %v", i, err)
+	}
+
+	// Three repeats are allowed, the fourth fails the repetition count test.
+	rct := []byte{1, 2, 3, 3, 3, 4, 5, 5, 5, 5, 6}
+	if i, err := runHealth(rct); i != 9 || err == nil || !strings.Contains(err.Error(), "repetition count") {
+		t.Errorf("the repeated samples failed at %d with %v, want 9 and the repetition count test", i, err)
+	}
+
+	// The first sample of a window is seen again every other sample, so the
+	// repetition count test passes and its 13th time fails the adaptive
+	// proportion test.
+	var apt []byte
+	for i := range 12 {
+		apt = append(apt, 0xaa, byte(i))
+	}
+	if i, err := runHealth(apt); err != nil {
+		t.Errorf("12 of the first sample failed at %d: %v", i, err)
+	}
+	apt = append(apt, 0xaa)
+	if i, err := runHealth(apt); i != 24 || err == nil || !strings.Contains(err.Error(), "adaptive proportion") {
+		t.Errorf("13 of the first sample failed at %d with %v, want 24 and the adaptive proportion test", i, err)
+	}
+
+	// The count starts again with each window.
+	window := make([]byte, aptWindow)
+	for i := range window {
+		window[i] = byte(i % 0xa0)
+	}
+	for i := 0; i < 24; i += 2 {
+		window[i] = 0xaa
+	}
+	if i, err := runHealth(append(window, window...)); err != nil {
+		t.Errorf("12 in each of two windows failed at %d: %v", i, err)
+	}
+}
+
+// TestFileSourceClose checks that the file source closes its file.
+func TestFileSourceClose(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "entropy.bin")
+	if err := os.WriteFile(name, make([]byte, 1<<10), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newSource("file", []string{name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Read(make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if err = closeSource(s); err != nil {
+		t.Fatal(err)
+	}
+	if err = closeSource(s); err == nil {
+		t.Errorf("the file was not closed")
+	}
+}
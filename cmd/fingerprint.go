@@ -0,0 +1,170 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The fingerprint of a machine is the SHA-256 hash of its canonical
+// serialization, which is the same no matter which output type the machine
+// was read from or written as.  The canonical serialization is:
+//
+//	"genProforma fingerprint v1\x00"
+//	uint32 length of the layout, followed by the layout ("rrprrprr")
+//	each rotor and permutator in layout order:
+//	    rotor:      'r', uint16 size, start, step, current,
+//	                uint32 length of the rotor bytes, followed by the rotor bytes
+//	    permutator: 'p', uint32 currentState, maximalStates,
+//	                uint32 number of cycles, followed by uint16 start, length,
+//	                current for each cycle, uint32 length of randp, followed by
+//	                the randp bytes
+//
+// All integers are big endian.  The bitPerm table is not included since it is
+// created from randp.  Any change to this serialization must change the
+// version in fingerprintTag.
+
+// fingerprintTag starts every canonical serialization so that fingerprints
+// cannot be confused with hashes of other data.
+const fingerprintTag = "genProforma fingerprint v1\x00"
+
+// canonical appends the canonical serialization of the rotor to b.
+func (r *Rotor) canonical(b []byte) []byte {
+	b = append(b, 'r')
+	b = binary.BigEndian.AppendUint16(b, uint16(r.Size))
+	b = binary.BigEndian.AppendUint16(b, uint16(r.Start))
+	b = binary.BigEndian.AppendUint16(b, uint16(r.Step))
+	b = binary.BigEndian.AppendUint16(b, uint16(r.Current))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(r.Rotor)))
+	return append(b, r.Rotor...)
+}
+
+// canonical appends the canonical serialization of the permutator to b.
+func (p *Permutator) canonical(b []byte) []byte {
+	b = append(b, 'p')
+	b = binary.BigEndian.AppendUint32(b, uint32(p.CurrentState))
+	b = binary.BigEndian.AppendUint32(b, uint32(p.MaximalStates))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(p.Cycles)))
+	for _, c := range p.Cycles {
+		b = binary.BigEndian.AppendUint16(b, uint16(c.Start))
+		b = binary.BigEndian.AppendUint16(b, uint16(c.Length))
+		b = binary.BigEndian.AppendUint16(b, uint16(c.Current))
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(p.Randp)))
+	return append(b, p.Randp...)
+}
+
+// canonical returns the canonical serialization of the machine: its layout
+// followed by each rotor and permutator in layout order.
+func (m *Machine) canonical() []byte {
+	b := []byte(fingerprintTag)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(m.Layout)))
+	b = append(b, m.Layout...)
+	for _, v := range m.components() {
+		b = componentCanonical(b, v)
+	}
+	return b
+}
+
+// componentCanonical appends the canonical serialization of a rotor or
+// permutator to b.
+func componentCanonical(b []byte, v any) []byte {
+	switch c := v.(type) {
+	case *Rotor:
+		b = c.canonical(b)
+	case *Permutator:
+		b = c.canonical(b)
+	}
+	return b
+}
+
+// fingerprint returns the SHA-256 hash of the canonical serialization of the
+// machine.
+func (m *Machine) fingerprint() [sha256.Size]byte {
+	return sha256.Sum256(m.canonical())
+}
+
+// componentFingerprint returns a short (64 bit) hex fingerprint of a single
+// rotor or permutator.
+func componentFingerprint(v any) string {
+	sum := sha256.Sum256(componentCanonical([]byte(fingerprintTag), v))
+	return hex.EncodeToString(sum[:8])
+}
+
+// fingerprintText returns the fingerprint of the machine in a form that is
+// easy to read aloud and compare: the first 128 bits of the hash as eight
+// groups of four upper case hex digits.
+func (m *Machine) fingerprintText() string {
+	sum := m.fingerprint()
+	groups := make([]string, 8)
+	for i := range groups {
+		groups[i] = strings.ToUpper(hex.EncodeToString(sum[2*i : 2*i+2]))
+	}
+	return strings.Join(groups, " ")
+}
+
+// machineMeta is the metadata written next to a machine written as json,
+// which has no place for the fingerprint comment of the other output types.
+type machineMeta struct {
+	File        string `json:"file"`
+	Layout      string `json:"layout"`
+	Source      string `json:"source"`
+	Fingerprint string `json:"fingerprint"`
+	SHA256      string `json:"sha256"`
+}
+
+// metaFileName returns the name of the metadata file for the named output
+// file.
+func metaFileName(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".meta.json"
+}
+
+// writeMeta writes the metadata of the machine written to the output file
+// name to its metadata file.
+func writeMeta(name string, m *Machine) error {
+	sum := m.fingerprint()
+	f, err := os.Create(metaFileName(name))
+	if err != nil {
+		return err
+	}
+	jEncoder := json.NewEncoder(f)
+	jEncoder.SetIndent("", "  ")
+	err = jEncoder.Encode(machineMeta{
+		File:        filepath.Base(name),
+		Layout:      m.Layout,
+		Source:      sourceName,
+		Fingerprint: m.fingerprintText(),
+		SHA256:      hex.EncodeToString(sum[:]),
+	})
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// fingerprintComment returns the fingerprint of the machine as comment lines,
+// each starting with leader, to be embedded in the generated output.
+func (m *Machine) fingerprintComment(leader string) string {
+	sum := m.fingerprint()
+	return leader + "Fingerprint: " + m.fingerprintText() + "\n" +
+		leader + "SHA-256:     " + hex.EncodeToString(sum[:]) + "\n"
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate source [args]",
+	Short: "Generate a new proforma machine from any registered source",
+	Long: `Generate a new proforma machine from the named source, passing it the rest of
+the arguments.  Any registered source can be used, including a source
+registered by code built into genProforma that has no command of its own, so
+"genProforma generate tntengine" is the same as "genProforma tntengine".  The
+flags of a source's own command, such as --label, are only available with
+that command.  The output type defaults to "json".  A source that is not
+registered is reported with the names of the registered sources.`,
+	Args: cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return Sources(), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if _, ok := sourceRegistry[name]; !ok {
+			return withExitCode(exitUsage, fmt.Errorf("%s is not a registered source, the sources are: %s",
+				name, strings.Join(Sources(), ", ")))
+		}
+		if err := checkOutputType("json"); err != nil {
+			return err
+		}
+		if err := useSource(name, args[1:]); err != nil {
+			return err
+		}
+		defer closeSource(source)
+		return generateProForma(outputType)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+}
@@ -0,0 +1,191 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The golden tests pin the exact output of genProforma.  After an intended
+// change to the output, rewrite the golden files with
+//
+//	go test ./cmd -run Golden -update
+//
+// and review the diff of testdata/golden.
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// countingReader is the deterministic stream of the golden tests: the bytes
+// 0, 1, 2, ... repeated.
+type countingReader struct {
+	next byte
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = c.next
+		c.next++
+	}
+	return len(p), nil
+}
+
+// goldenSource returns a new deterministic Source for the golden tests.
+func goldenSource() Source {
+	return NewStreamSource(new(countingReader).Read)
+}
+
+// checkGolden compares got with the named golden file, or rewrites the file
+// if -update is given.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./cmd -run Golden -update to create it)", err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := range max(len(gotLines), len(wantLines)) {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Errorf("the output differs from %s at line %d:\n got: %q\nwant: %q", path, i+1, g, w)
+			return
+		}
+	}
+}
+
+// TestGoldenOutput pins the output of generateProForma for every output type.
+func TestGoldenOutput(t *testing.T) {
+	savedSource, savedName, savedFile := source, sourceName, outputFileName
+	defer func() {
+		source, sourceName, outputFileName = savedSource, savedName, savedFile
+	}()
+	for _, oType := range outputTypes {
+		t.Run(oType, func(t *testing.T) {
+			source, sourceName = goldenSource(), "golden"
+			outputFileName = filepath.Join(t.TempDir(), "proforma"+outputExtensions[oType])
+			if err := generateProForma(oType); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(outputFileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "proforma."+oType+".golden", got)
+			meta, err := os.ReadFile(metaFileName(outputFileName))
+			if oType != "json" {
+				if err == nil {
+					t.Errorf("metadata was written for the %s output", oType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "proforma.meta.json.golden", meta)
+		})
+	}
+}
+
+// TestGoldenString pins the Go source written for a single rotor and
+// permutator.
+func TestGoldenString(t *testing.T) {
+	m, err := generateMachine(goldenSource(), engineLayout, rotorSizes, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"", "\t\t"} {
+		name := "noprefix"
+		if len(p) != 0 {
+			name = "prefix"
+		}
+		checkGolden(t, "rotor."+name+".golden", []byte(m.Rotors[0].goSource(p)))
+		checkGolden(t, "permutator."+name+".golden", []byte(m.Permutators[0].goSource(p)))
+	}
+	if m.Rotors[0].String() != m.Rotors[0].goSource("") || m.Permutators[0].String() != m.Permutators[0].goSource("") {
+		t.Errorf("String does not write the Go source without a prefix")
+	}
+}
+
+// TestFixedSizes checks that the C and Rust output types, which declare the
+// rotors and permutators as arrays, refuse components of different sizes.
+func TestFixedSizes(t *testing.T) {
+	for name, change := range map[string]func(m *Machine){
+		"rotor bytes":  func(m *Machine) { m.Rotors[1].Rotor = append(m.Rotors[1].Rotor, 0) },
+		"cycle counts": func(m *Machine) { m.Permutators[1].Cycles = m.Permutators[1].Cycles[:3] },
+	} {
+		m, err := generateMachine(goldenSource(), engineLayout, rotorSizes, cycleSizes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		change(m)
+		for _, oType := range outputTypes {
+			err := writeProForma(io.Discard, oType, m)
+			if fixed := oType == "c" || oType == "rust"; fixed != (err != nil) {
+				t.Errorf("%s, %s output: error %v", name, oType, err)
+			}
+		}
+	}
+}
+
+// TestConvertGolden checks that convert infers each output type from the
+// name of the output file, even after it inferred another type.
+func TestConvertGolden(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", "golden", "proforma.json.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := filepath.Join(dir, "proforma.json")
+	if err = os.WriteFile(in, src, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, oType := range []string{"c", "rust", "python"} {
+		out := filepath.Join(dir, "proforma"+outputExtensions[oType])
+		var stderr bytes.Buffer
+		jsonErrors = false
+		if code := run([]string{"convert", in, out}, &stderr); code != exitOK {
+			t.Fatalf("convert to %s: exit code %d (%s)", out, code, stderr.String())
+		}
+		got, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "proforma."+oType+".golden", got)
+	}
+}
@@ -0,0 +1,308 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/bgallie/genProforma/proformapb"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var grpcListenAddr string
+
+// grpcCmd represents the grpc command
+var grpcCmd = &cobra.Command{
+	Use:   "grpc",
+	Short: "Serve a gRPC API that generates and verifies proforma machines",
+	Long: `Serve the genproforma.v1.Proforma gRPC service defined in
+proformapb/proforma.proto, with the Generate, Verify, Convert and Fingerprint
+methods.  The messages mirror the Rotor, Cycle and Permutator types, and clients
+in other languages can be generated from the .proto file.
+
+Only the backends given by --backend may be used, and only the random,
+tntengine, ikmachine and mixed backends can be served.  With --tls-cert and
+--tls-key the server uses TLS, and with --client-ca it also requires client
+certificates signed by that CA (mTLS).  Each client, identified by the common
+name of its certificate or else by its address, is limited to --rate requests
+per second.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serveGRPC()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(grpcCmd)
+	grpcCmd.Flags().StringVar(&grpcListenAddr, "listen", "localhost:9090", "address to listen on")
+	addServerFlags(grpcCmd)
+}
+
+func serveGRPC() error {
+	if err := checkServeBackends(); err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		return err
+	}
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	if rateLimit > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(rateLimitInterceptor(newRateLimiter(rateLimit, rateBurst))))
+	}
+	srv := grpc.NewServer(opts...)
+	proformapb.RegisterProformaServer(srv, &grpcServer{backends: serveBackends})
+
+	lis, err := net.Listen("tcp", grpcListenAddr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+	log.Printf("Serving the proforma gRPC API on %s", lis.Addr())
+	return srv.Serve(lis)
+}
+
+// rateLimitInterceptor limits each client to the rate allowed by l.
+func rateLimitInterceptor(l *rateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var id string
+		if p, ok := peer.FromContext(ctx); ok {
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+				id = peerID(&tlsInfo.State, p.Addr.String())
+			} else {
+				id = peerID(nil, p.Addr.String())
+			}
+		}
+		if wait, ok := l.allow(id); !ok {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %s", wait)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// grpcServer implements the Proforma gRPC service.
+type grpcServer struct {
+	proformapb.UnimplementedProformaServer
+	backends []string
+}
+
+func (s *grpcServer) Generate(ctx context.Context, req *proformapb.GenerateRequest) (*proformapb.GenerateResponse, error) {
+	layout, backend := req.GetLayout(), req.GetBackend()
+	if len(layout) == 0 {
+		layout = engineLayout
+	}
+	if len(backend) == 0 {
+		backend = defaultBackend
+	}
+	if !slices.Contains(s.backends, backend) {
+		return nil, status.Errorf(codes.InvalidArgument, "the %s backend is not available", backend)
+	}
+	if oType := req.GetOutputType(); len(oType) != 0 && !slices.Contains(outputTypes, oType) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not a valid output type", oType)
+	}
+	m, err := generateFrom(backend, layout, req.GetSecret(), req.GetLabel())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	sum := m.fingerprint()
+	res := &proformapb.GenerateResponse{
+		Machine:     machineToPB(m),
+		Backend:     backend,
+		Fingerprint: m.fingerprintText(),
+		Sha256:      hex.EncodeToString(sum[:]),
+	}
+	if oType := req.GetOutputType(); len(oType) != 0 {
+		var buf bytes.Buffer
+		if err := writeProForma(&buf, oType, m); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		res.Formatted = buf.Bytes()
+	}
+	return res, nil
+}
+
+func (s *grpcServer) Verify(ctx context.Context, req *proformapb.VerifyRequest) (*proformapb.VerifyResponse, error) {
+	m, err := machineInput(req.GetMachine(), req.GetEncoded())
+	if err == nil {
+		err = m.verify()
+	}
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, err
+		}
+		return &proformapb.VerifyResponse{Errors: errorList(err)}, nil
+	}
+	sum := m.fingerprint()
+	return &proformapb.VerifyResponse{
+		Valid:       true,
+		Layout:      m.Layout,
+		Fingerprint: m.fingerprintText(),
+		Sha256:      hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+func (s *grpcServer) Convert(ctx context.Context, req *proformapb.ConvertRequest) (*proformapb.ConvertResponse, error) {
+	if !slices.Contains(outputTypes, req.GetOutputType()) {
+		return nil, status.Errorf(codes.InvalidArgument, "%q is not a valid output type", req.GetOutputType())
+	}
+	m, err := checkedMachineInput(req.GetMachine(), req.GetEncoded())
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeProForma(&buf, req.GetOutputType(), m); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &proformapb.ConvertResponse{Data: buf.Bytes()}, nil
+}
+
+func (s *grpcServer) Fingerprint(ctx context.Context, req *proformapb.FingerprintRequest) (*proformapb.FingerprintResponse, error) {
+	m, err := checkedMachineInput(req.GetMachine(), req.GetEncoded())
+	if err != nil {
+		return nil, err
+	}
+	sum := m.fingerprint()
+	return &proformapb.FingerprintResponse{
+		Fingerprint: m.fingerprintText(),
+		Sha256:      hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// machineInput returns the machine given in a request, either as a message or
+// encoded as one of the input types.  It returns an InvalidArgument status if
+// no machine is given, and the load error if the encoded machine cannot be
+// loaded.
+func machineInput(pm *proformapb.Machine, encoded *proformapb.EncodedMachine) (*Machine, error) {
+	switch {
+	case pm != nil:
+		return machineFromPB(pm)
+	case encoded != nil:
+		iType := encoded.GetType()
+		if len(iType) == 0 {
+			iType = "json"
+		}
+		if !slices.Contains(inputTypes, iType) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is not a valid input type", iType)
+		}
+		return loadProForma(bytes.NewReader(encoded.GetData()), iType)
+	}
+	return nil, status.Error(codes.InvalidArgument, "no machine was given")
+}
+
+// checkedMachineInput returns the machine given in a request, with an
+// InvalidArgument status if it is not well formed.
+func checkedMachineInput(pm *proformapb.Machine, encoded *proformapb.EncodedMachine) (*Machine, error) {
+	m, err := machineInput(pm, encoded)
+	if err == nil {
+		err = m.check()
+	}
+	if err != nil && status.Code(err) != codes.InvalidArgument {
+		err = status.Error(codes.InvalidArgument, err.Error())
+	}
+	return m, err
+}
+
+// machineToPB returns the machine as a protobuf message.
+func machineToPB(m *Machine) *proformapb.Machine {
+	pm := &proformapb.Machine{Layout: m.Layout}
+	for _, r := range m.Rotors {
+		pm.Rotors = append(pm.Rotors, &proformapb.Rotor{
+			Size:    int32(r.Size),
+			Start:   int32(r.Start),
+			Step:    int32(r.Step),
+			Current: int32(r.Current),
+			Rotor:   r.Rotor,
+		})
+	}
+	for _, p := range m.Permutators {
+		pp := &proformapb.Permutator{
+			CurrentState:  p.CurrentState,
+			MaximalStates: p.MaximalStates,
+			Randp:         p.Randp,
+		}
+		for _, c := range p.Cycles {
+			pp.Cycles = append(pp.Cycles, &proformapb.Cycle{
+				Start:   int32(c.Start),
+				Length:  int32(c.Length),
+				Current: int32(c.Current),
+			})
+		}
+		pm.Permutators = append(pm.Permutators, pp)
+	}
+	return pm
+}
+
+// machineFromPB returns the machine in the protobuf message.  It is an error
+// for a value to be out of range of the field it is stored in.
+func machineFromPB(pm *proformapb.Machine) (*Machine, error) {
+	m := &Machine{Layout: pm.GetLayout()}
+	for i, pr := range pm.GetRotors() {
+		r := &Rotor{Rotor: pr.GetRotor()}
+		names := []string{"size", "start", "step", "current"}
+		for j, v := range []int32{pr.GetSize(), pr.GetStart(), pr.GetStep(), pr.GetCurrent()} {
+			if v < math.MinInt16 || v > math.MaxInt16 {
+				return nil, fmt.Errorf("rotor %d: %s %d is out of range", i+1, names[j], v)
+			}
+		}
+		r.Size, r.Start, r.Step, r.Current = int16(pr.GetSize()), int16(pr.GetStart()), int16(pr.GetStep()), int16(pr.GetCurrent())
+		m.Rotors = append(m.Rotors, r)
+	}
+	for i, pp := range pm.GetPermutators() {
+		p := &Permutator{
+			CurrentState:  pp.GetCurrentState(),
+			MaximalStates: pp.GetMaximalStates(),
+			Randp:         pp.GetRandp(),
+		}
+		for j, pc := range pp.GetCycles() {
+			names := []string{"start", "length", "current"}
+			for k, v := range []int32{pc.GetStart(), pc.GetLength(), pc.GetCurrent()} {
+				if v < math.MinInt16 || v > math.MaxInt16 {
+					return nil, fmt.Errorf("permutator %d: cycle %d: %s %d is out of range", i+1, j+1, names[k], v)
+				}
+			}
+			p.Cycles = append(p.Cycles, Cycle{
+				Start:   int16(pc.GetStart()),
+				Length:  int16(pc.GetLength()),
+				Current: int16(pc.GetCurrent()),
+			})
+		}
+		m.Permutators = append(m.Permutators, p)
+	}
+	return m, nil
+}
@@ -0,0 +1,252 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/bgallie/genProforma/proformapb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// grpcClient serves the Proforma service in process over a bufconn
+// listener, allowing the backends, and returns a client for it.
+func grpcClient(t *testing.T, backends []string, opts ...grpc.ServerOption) proformapb.ProformaClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	proformapb.RegisterProformaServer(srv, &grpcServer{backends: backends})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proformapb.NewProformaClient(conn)
+}
+
+// wantCode checks that err has the status code.
+func wantCode(t *testing.T, what string, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("%s: got %v, want the status %s", what, err, code)
+	}
+}
+
+func TestGRPCGenerate(t *testing.T) {
+	client := grpcClient(t, []string{"random", "tntengine"})
+	ctx := context.Background()
+
+	res, err := client.Generate(ctx, &proformapb.GenerateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetBackend() != defaultBackend || res.GetMachine().GetLayout() != engineLayout {
+		t.Errorf("an empty request used the %s backend and the %s layout", res.GetBackend(), res.GetMachine().GetLayout())
+	}
+	m, err := machineFromPB(res.GetMachine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.verify(); err != nil {
+		t.Errorf("the generated machine is not valid: %v", err)
+	}
+	if m.fingerprintText() != res.GetFingerprint() {
+		t.Errorf("the fingerprint is not the fingerprint of the machine")
+	}
+	if len(res.GetFormatted()) != 0 {
+		t.Errorf("the machine was formatted without an output type")
+	}
+
+	keyed := &proformapb.GenerateRequest{Backend: "tntengine", Secret: "secret", Label: "a", OutputType: "ikm"}
+	first, err := client.Generate(ctx, keyed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := client.Generate(ctx, keyed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.GetSha256() != again.GetSha256() {
+		t.Errorf("the tntengine backend gave two machines for the same secret")
+	}
+	formatted, err := loadProForma(bytes.NewReader(first.GetFormatted()), "ikm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted.fingerprintText() != first.GetFingerprint() {
+		t.Errorf("the formatted machine is not the generated machine")
+	}
+
+	for name, req := range map[string]*proformapb.GenerateRequest{
+		"backend not allowed": {Backend: "ikmachine", Secret: "secret"},
+		"output type":         {OutputType: "xml"},
+		"layout":              {Layout: "rrx"},
+		"no secret":           {Backend: "tntengine"},
+	} {
+		_, err := client.Generate(ctx, req)
+		wantCode(t, name, err, codes.InvalidArgument)
+	}
+
+	// The default backend must be allowed like any other.
+	_, err = grpcClient(t, []string{"tntengine"}).Generate(ctx, &proformapb.GenerateRequest{Secret: "secret"})
+	wantCode(t, "default backend not allowed", err, codes.InvalidArgument)
+}
+
+func TestGRPCVerifyConvertFingerprint(t *testing.T) {
+	client := grpcClient(t, []string{"random"})
+	ctx := context.Background()
+	m, err := generateMachine(goldenSource(), engineLayout, rotorSizes, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var json bytes.Buffer
+	if err := writeProForma(&json, "json", m); err != nil {
+		t.Fatal(err)
+	}
+	pm := machineToPB(m)
+	encoded := &proformapb.EncodedMachine{Type: "json", Data: json.Bytes()}
+
+	for name, req := range map[string]*proformapb.VerifyRequest{
+		"message": {Input: &proformapb.VerifyRequest_Machine{Machine: pm}},
+		"encoded": {Input: &proformapb.VerifyRequest_Encoded{Encoded: encoded}},
+	} {
+		res, err := client.Verify(ctx, req)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !res.GetValid() || res.GetFingerprint() != m.fingerprintText() || len(res.GetErrors()) != 0 {
+			t.Errorf("%s: got %v, want a valid machine", name, res)
+		}
+	}
+	invalid := machineToPB(m)
+	invalid.Rotors[0].Start = invalid.Rotors[0].Size
+	res, err := client.Verify(ctx, &proformapb.VerifyRequest{Input: &proformapb.VerifyRequest_Machine{Machine: invalid}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetValid() || len(res.GetErrors()) == 0 {
+		t.Errorf("an invalid machine was verified: %v", res)
+	}
+	_, err = client.Verify(ctx, &proformapb.VerifyRequest{})
+	wantCode(t, "verify without a machine", err, codes.InvalidArgument)
+	_, err = client.Verify(ctx, &proformapb.VerifyRequest{Input: &proformapb.VerifyRequest_Encoded{
+		Encoded: &proformapb.EncodedMachine{Type: "c", Data: json.Bytes()}}})
+	wantCode(t, "verify of an input type", err, codes.InvalidArgument)
+
+	for _, oType := range outputTypes {
+		res, err := client.Convert(ctx, &proformapb.ConvertRequest{
+			Input:      &proformapb.ConvertRequest_Machine{Machine: pm},
+			OutputType: oType,
+		})
+		if err != nil {
+			t.Fatalf("convert to %s: %v", oType, err)
+		}
+		var want bytes.Buffer
+		if err := writeProForma(&want, oType, m); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(res.GetData(), want.Bytes()) {
+			t.Errorf("convert to %s: the output differs from the %s output type", oType, oType)
+		}
+	}
+	_, err = client.Convert(ctx, &proformapb.ConvertRequest{
+		Input:      &proformapb.ConvertRequest_Encoded{Encoded: encoded},
+		OutputType: "xml",
+	})
+	wantCode(t, "convert to an unknown type", err, codes.InvalidArgument)
+	_, err = client.Convert(ctx, &proformapb.ConvertRequest{
+		Input:      &proformapb.ConvertRequest_Encoded{Encoded: &proformapb.EncodedMachine{Data: []byte("[")}},
+		OutputType: "json",
+	})
+	wantCode(t, "convert of a malformed machine", err, codes.InvalidArgument)
+
+	fp, err := client.Fingerprint(ctx, &proformapb.FingerprintRequest{Input: &proformapb.FingerprintRequest_Encoded{Encoded: encoded}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp.GetFingerprint() != m.fingerprintText() {
+		t.Errorf("fingerprint = %s, want %s", fp.GetFingerprint(), m.fingerprintText())
+	}
+	_, err = client.Fingerprint(ctx, &proformapb.FingerprintRequest{})
+	wantCode(t, "fingerprint without a machine", err, codes.InvalidArgument)
+}
+
+// TestMachineFromPBRange checks that values that do not fit the fields of a
+// machine are rejected instead of being truncated.
+func TestMachineFromPBRange(t *testing.T) {
+	m, err := generateMachine(goldenSource(), "rp", rotorSizes, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, change := range map[string]func(pm *proformapb.Machine){
+		"rotor size":    func(pm *proformapb.Machine) { pm.Rotors[0].Size = math.MaxInt16 + 1 },
+		"rotor start":   func(pm *proformapb.Machine) { pm.Rotors[0].Start = math.MinInt16 - 1 },
+		"rotor step":    func(pm *proformapb.Machine) { pm.Rotors[0].Step = math.MaxInt32 },
+		"rotor current": func(pm *proformapb.Machine) { pm.Rotors[0].Current = 1 << 16 },
+		"cycle start":   func(pm *proformapb.Machine) { pm.Permutators[0].Cycles[0].Start = 1 << 20 },
+		"cycle length":  func(pm *proformapb.Machine) { pm.Permutators[0].Cycles[1].Length = -(1 << 20) },
+		"cycle current": func(pm *proformapb.Machine) { pm.Permutators[0].Cycles[3].Current = math.MinInt32 },
+	} {
+		pm := machineToPB(m)
+		change(pm)
+		if _, err := machineFromPB(pm); err == nil {
+			t.Errorf("%s: the out of range value was accepted", name)
+		}
+	}
+	back, err := machineFromPB(machineToPB(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.fingerprint() != m.fingerprint() {
+		t.Errorf("the machine changed on the way to and from protobuf")
+	}
+}
+
+// TestGRPCRateLimit checks that the interceptor limits each client.
+func TestGRPCRateLimit(t *testing.T) {
+	client := grpcClient(t, []string{"random"}, grpc.UnaryInterceptor(rateLimitInterceptor(newRateLimiter(0.001, 1))))
+	ctx := context.Background()
+	if _, err := client.Generate(ctx, &proformapb.GenerateRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Generate(ctx, &proformapb.GenerateRequest{})
+	wantCode(t, "a request over the limit", err, codes.ResourceExhausted)
+}
+
+// TestGRPCGoroutines checks that the tntengine backend is shut down after
+// each request.
+func TestGRPCGoroutines(t *testing.T) {
+	client := grpcClient(t, []string{"tntengine"})
+	checkGoroutines(t, func() {
+		if _, err := client.Generate(context.Background(), &proformapb.GenerateRequest{Backend: "tntengine", Secret: "secret"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
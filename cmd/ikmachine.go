@@ -16,76 +16,45 @@ limitations under the License.
 package cmd
 
 import (
-	"fmt"
-	"os"
-	"strings"
-
 	"github.com/bgallie/ikmachine"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"golang.org/x/term"
-)
-
-var (
-	ikengine *ikmachine.IkMachine
-	ikRandom *ikmachine.Rand
 )
 
-// tntengineCmd represents the tntengine command
+// ikmachineCmd represents the ikmachine command
 var ikmachineCmd = &cobra.Command{
 	Use:   "ikmachine",
 	Short: "Generate a new proforma machine",
-	Long:  `Generate a new proforma machine using a psudo-random number generator (ikmachine).`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if rootCmd.Flags().Changed("outputType") {
-			if outputType != "json" && outputType != "ikm" {
-				cobra.CheckErr(outputType + " is not a valid output type.")
-			}
-		} else {
-			rootCmd.Flags().Set("outputType", "ikm")
-		}
-		initEngine(args)
-		generateProForma(outputType)
+	Long: `Generate a new proforma machine using a psudo-random number generator (ikmachine).
+
+The rotor and permutator values are chosen from the random bytes with
+rejection sampling, so they are uniformly distributed.  Releases before the
+rejection sampler keyed tntengine, not ikmachine, for this command; use
+"tntengine --legacy-sampling -t ikm" to make the machine those releases made.`,
+	Annotations: map[string]string{sourceAnnotation: "ikmachine"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCommand(cmd, args, "ikm")
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(ikmachineCmd)
+	ikmachineCmd.Flags().StringVar(&label, "label", "", `Derive the key from the passphrase (the master secret) and this label
+	using HKDF, so each label reproduces its own machine.`)
+	RegisterSource("ikmachine", newIkSource)
 }
 
-func initIkEngine(args []string) {
-	// Obtain the passphrase used to encrypt the file from either:
-	// 1. User input from the terminal (most secure)
-	// 2. The 'TNT2_SECRET' environment variable (less secure)
-	// 3. Arguments from the entered command line (least secure - not recommended)
-	var secret string
-	if len(args) == 0 {
-		if viper.IsSet("GPF_SECRET") {
-			secret = viper.GetString("GPF_SECRET")
-		} else {
-			if term.IsTerminal(int(os.Stdin.Fd())) {
-				fmt.Fprintf(os.Stderr, "Enter the passphrase: ")
-				byteSecret, err := term.ReadPassword(int(os.Stdin.Fd()))
-				cobra.CheckErr(err)
-				fmt.Fprintln(os.Stderr, "")
-				secret = string(byteSecret)
-			}
-		}
-	} else {
-		secret = strings.Join(args, " ")
-	}
-
-	if len(secret) == 0 {
-		cobra.CheckErr("You must supply a password.")
-		// } else {
-		// 	fmt.Printf("Secret: [%s]\n", secret)
+// newIkSource returns a Source that reads the stream of pseudo-random bytes
+// from an ikmachine keyed with the secret.
+func newIkSource(args []string) (Source, error) {
+	secret, err := getSecret(args)
+	if err != nil {
+		return nil, err
 	}
 
 	// Initialize the ikmachine with the secret key and the named proforma file.
-	ikengine = new(ikmachine.IkMachine).InitializeProformaEngine().ApplyKey('E', []byte(secret))
-	// Get the random functions
-	ikRandom = new(ikmachine.Rand).New(ikengine)
-	rRead = ikRandom.Read
-	rPerm = ikRandom.Perm
-	rInt = ikRandom.Int63n
+	ikengine := new(ikmachine.IkMachine).InitializeProformaEngine().ApplyKey('E', []byte(secret))
+	// The engine's own Int63n and Perm are not used so that all of the
+	// sources are sampled the same way.
+	ikRandom := new(ikmachine.Rand).New(ikengine)
+	return NewStreamSource(ikRandom.Read), nil
 }
@@ -0,0 +1,154 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [flags] file",
+	Short: "Display a proforma machine with derived statistics",
+	Long: `Display each rotor and permutator of a proforma machine in layout order
+with its size, start, step, bit balance, period, maximal states and a short
+fingerprint, followed by the statistics and fingerprint of the whole machine.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkInputType(); err != nil {
+			return err
+		}
+		m, err := loadProFormaFile(args[0], fromType)
+		if err != nil {
+			return withExitCode(exitInput, err)
+		}
+		inspectMachine(os.Stdout, m)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.Flags().StringVar(&fromType, "from", "", `Type of the input file ("json" or "ikm")`)
+}
+
+// inspectMachine writes the description of the machine m to w.
+func inspectMachine(w io.Writer, m *Machine) {
+	fmt.Fprintf(w, "layout: %s\n", m.Layout)
+	for i, v := range m.components() {
+		switch c := v.(type) {
+		case *Rotor:
+			ones := bitCount(c.Rotor, int(c.Size))
+			fmt.Fprintf(w, "component %d: rotor\n", i+1)
+			fmt.Fprintf(w, "\tsize:          %d\n", c.Size)
+			fmt.Fprintf(w, "\tstart:         %d\n", c.Start)
+			fmt.Fprintf(w, "\tstep:          %d\n", c.Step)
+			fmt.Fprintf(w, "\tbit balance:   %d ones of %d bits (%.2f%%)\n",
+				ones, c.Size, 100*float64(ones)/float64(c.Size))
+			fmt.Fprintf(w, "\tperiod:        %s\n", componentPeriod(c))
+			fmt.Fprintf(w, "\tfingerprint:   %s\n", componentFingerprint(c))
+		case *Permutator:
+			lengths := make([]int16, len(c.Cycles))
+			for j, cycle := range c.Cycles {
+				lengths[j] = cycle.Length
+			}
+			fmt.Fprintf(w, "component %d: permutator\n", i+1)
+			fmt.Fprintf(w, "\tsize:          %d\n", len(c.Randp))
+			fmt.Fprintf(w, "\tcycles:        %v\n", lengths)
+			fmt.Fprintf(w, "\tperiod:        %s\n", componentPeriod(c))
+			fmt.Fprintf(w, "\tmaximalStates: %d\n", c.MaximalStates)
+			fmt.Fprintf(w, "\tfingerprint:   %s\n", componentFingerprint(c))
+		}
+	}
+	sum := m.fingerprint()
+	fmt.Fprintln(w, "machine:")
+	fmt.Fprintf(w, "\tperiod:        %s\n", machinePeriod(m))
+	fmt.Fprintf(w, "\tmaximalStates: %s\n", machineMaximalStates(m))
+	fmt.Fprintf(w, "\tfingerprint:   %s\n", m.fingerprintText())
+	fmt.Fprintf(w, "\tSHA-256:       %s\n", hex.EncodeToString(sum[:]))
+}
+
+// bitCount returns the number of bits set in the first nBits bits of data.
+func bitCount(data []byte, nBits int) int {
+	cnt := 0
+	for i := 0; i < nBits/8; i++ {
+		cnt += bits.OnesCount8(data[i])
+	}
+	if rem := nBits & 7; rem != 0 {
+		cnt += bits.OnesCount8(data[nBits/8] & (0xff >> (8 - rem)))
+	}
+	return cnt
+}
+
+// componentPeriod returns the number of blocks a rotor or permutator can
+// process before returning to its starting state.  A rotor returns to its
+// start after size/gcd(size, step) steps, and a permutator after the least
+// common multiple of its cycle lengths.
+func componentPeriod(v any) *big.Int {
+	switch c := v.(type) {
+	case *Rotor:
+		size := big.NewInt(int64(c.Size))
+		gcd := new(big.Int).GCD(nil, nil, size, big.NewInt(int64(c.Step)))
+		return size.Div(size, gcd)
+	case *Permutator:
+		period := big.NewInt(1)
+		for _, cycle := range c.Cycles {
+			period = lcm(period, big.NewInt(int64(cycle.Length)))
+		}
+		return period
+	}
+	return big.NewInt(1)
+}
+
+// machinePeriod returns the number of blocks the machine can process before
+// all of its rotors and permutators are back in their starting state.
+func machinePeriod(m *Machine) *big.Int {
+	period := big.NewInt(1)
+	for _, v := range m.components() {
+		period = lcm(period, componentPeriod(v))
+	}
+	return period
+}
+
+// machineMaximalStates returns the product of the rotor sizes and the
+// permutators' maximal states, calculated the same way as the engines do.
+func machineMaximalStates(m *Machine) *big.Int {
+	states := big.NewInt(1)
+	for _, r := range m.Rotors {
+		states.Mul(states, big.NewInt(int64(r.Size)))
+	}
+	for _, p := range m.Permutators {
+		states.Mul(states, big.NewInt(int64(p.MaximalStates)))
+	}
+	return states
+}
+
+// lcm returns the least common multiple of a and b.
+func lcm(a, b *big.Int) *big.Int {
+	if a.Sign() == 0 || b.Sign() == 0 {
+		return new(big.Int)
+	}
+	gcd := new(big.Int).GCD(nil, nil, a, b)
+	res := new(big.Int).Div(a, gcd)
+	return res.Abs(res.Mul(res, b))
+}
@@ -0,0 +1,339 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"os"
+	"path/filepath"
+	dbug "runtime/debug"
+	"strings"
+
+	"github.com/bgallie/jc1"
+	"github.com/bgallie/tntengine"
+)
+
+var (
+	katVectors   bool
+	katStreamLen int
+)
+
+// katKey is the key used for the known-answer tests, and katBlocks the number
+// of plaintext blocks encrypted.
+const (
+	katKey    = "genProforma known-answer test"
+	katBlocks = 4
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&katVectors, "kat", false, `Write known-answer test vectors for each machine to a file next to it, named
+	like the output file with the extension ".kat.json".  The vectors are a fixed
+	key and plaintext, the ciphertext from tntengine built with the machine as its
+	proforma and keyed with the key, and the start of its keyed random stream.
+	The vectors are produced by tntengine, and name it as their engine, so they
+	cannot be written for the "ikm" output type, which is built into ikmachine.`)
+	rootCmd.PersistentFlags().IntVar(&katStreamLen, "kat-stream", 256,
+		"number of bytes of the keyed random stream written to the test vectors")
+}
+
+// katVector holds the known-answer tests for a machine.  The byte strings are
+// hex encoded.
+type katVector struct {
+	Engine        string `json:"engine"`
+	EngineVersion string `json:"engineVersion"`
+	Fingerprint   string `json:"fingerprint"`
+	SHA256        string `json:"sha256"`
+	Key           string `json:"key"`
+	Index         int    `json:"index"`
+	Plaintext     string `json:"plaintext"`
+	Ciphertext    string `json:"ciphertext"`
+	Stream        string `json:"stream"`
+}
+
+// errKatIkm is returned when test vectors are asked for a machine built into
+// ikmachine, as they would be the vectors of tntengine.
+var errKatIkm = withExitCode(exitUsage, errors.New(`--kat writes the tntengine test vectors, so it cannot be used with the "ikm" output type`))
+
+// katFileName returns the name of the test vector file for the named output
+// file.
+func katFileName(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".kat.json"
+}
+
+// katPlaintext returns the plaintext of the known-answer tests, the byte
+// values counting up from 0.
+func katPlaintext() []byte {
+	res := make([]byte, katBlocks*tntengine.CipherBlockBytes)
+	for i := range res {
+		res[i] = byte(i)
+	}
+	return res
+}
+
+// newKatVector runs the known-answer tests on the machine.  The plaintext is
+// encrypted from block 0 of the keyed engine, and the stream is read from a
+// second keyed engine the way a tntengine Rand reads it.
+func newKatVector(m *Machine) (*katVector, error) {
+	if katStreamLen < 0 {
+		return nil, withExitCode(exitUsage, fmt.Errorf("%d is not a valid length for the random stream", katStreamLen))
+	}
+	e, err := newTntKeyed(m, []byte(katKey))
+	if err != nil {
+		return nil, err
+	}
+	e.setIndex(0)
+	plain := katPlaintext()
+	var ct []byte
+	for i := 0; i < len(plain); i += tntengine.CipherBlockBytes {
+		ct = append(ct, e.encrypt(plain[i:i+tntengine.CipherBlockBytes])...)
+	}
+	if e, err = newTntKeyed(m, []byte(katKey)); err != nil {
+		return nil, err
+	}
+	stream := make([]byte, katStreamLen)
+	if _, err = e.rand().Read(stream); err != nil {
+		return nil, err
+	}
+	sum := m.fingerprint()
+	return &katVector{
+		Engine:        "tntengine",
+		EngineVersion: moduleVersion("github.com/bgallie/tntengine"),
+		Fingerprint:   m.fingerprintText(),
+		SHA256:        hex.EncodeToString(sum[:]),
+		Key:           hex.EncodeToString([]byte(katKey)),
+		Index:         0,
+		Plaintext:     hex.EncodeToString(plain),
+		Ciphertext:    hex.EncodeToString(ct),
+		Stream:        hex.EncodeToString(stream),
+	}, nil
+}
+
+// writeKat writes the known-answer tests for the machine to the named file.
+func writeKat(name string, m *Machine) error {
+	kat, err := newKatVector(m)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	jEncoder := json.NewEncoder(f)
+	jEncoder.SetIndent("", "  ")
+	err = jEncoder.Encode(kat)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// moduleVersion returns the version of the named module that genProforma was
+// built with.
+func moduleVersion(path string) string {
+	if bi, ok := dbug.ReadBuildInfo(); ok {
+		for _, d := range bi.Deps {
+			if d.Path == path {
+				return d.Version
+			}
+		}
+	}
+	return "unknown"
+}
+
+// tntKeyed is a tntengine keyed with a secret, using a generated machine as
+// its proforma machine.  The proforma machine is compiled into tntengine, so
+// TntEngine.Init is followed step by step here with the exported rotors and
+// permutators.  The counter that ends the tntengine machine does not change
+// the blocks and is left out.
+type tntKeyed struct {
+	key     *jc1.UberJc1
+	engine  []tntengine.Cryptor
+	cntrKey tntengine.CipherBlock
+}
+
+// newTntKeyed keys tntengine with the secret, using the machine as the
+// proforma machine.
+func newTntKeyed(m *Machine, secret []byte) (*tntKeyed, error) {
+	if m.Layout != tntengine.EngineLayout {
+		return nil, fmt.Errorf("tntengine needs a machine with the layout %q, not %q", tntengine.EngineLayout, m.Layout)
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	e := &tntKeyed{key: new(jc1.UberJc1).New(secret), engine: tntCryptors(m)}
+	e.setIndex(0)
+	e.cntrKey = e.encrypt(e.key.XORKeyStream(make([]byte, tntengine.CipherBlockBytes)))
+
+	// Update the rotors and the first permutator from the random stream of
+	// the proforma machine, which is changed as it is updated.  The other
+	// permutators are copies of the first.
+	random := e.rand()
+	var rotors, permutators []tntengine.Cryptor
+	for _, c := range e.engine {
+		switch v := c.(type) {
+		case *tntengine.Rotor:
+			random.updateRotor(v)
+			rotors = append(rotors, v)
+		case *tntengine.Permutator:
+			if len(permutators) == 0 {
+				random.updatePermutator(v)
+				permutators = append(permutators, v)
+			} else {
+				p := permutators[0].(*tntengine.Permutator)
+				permutators = append(permutators, new(tntengine.Permutator).New(p.Cycle.Length, p.Randp))
+			}
+		}
+	}
+	rotorOrder := random.perm(len(rotors))
+	keyed := make([]tntengine.Cryptor, 0, len(m.Layout))
+	rIdx, pIdx := 0, 0
+	for _, v := range m.Layout {
+		switch v {
+		case 'r':
+			keyed = append(keyed, rotors[rotorOrder[rIdx]])
+			rIdx++
+		case 'p':
+			keyed = append(keyed, permutators[pIdx])
+			pIdx++
+		}
+	}
+	e.engine = keyed
+	e.cntrKey = e.encrypt(e.key.XORKeyStream(make([]byte, tntengine.CipherBlockBytes)))
+	return e, nil
+}
+
+// setIndex sets the rotors and permutators to the state for the given block.
+func (e *tntKeyed) setIndex(idx int64) {
+	for _, c := range e.engine {
+		c.SetIndex(big.NewInt(idx))
+	}
+}
+
+// encrypt encrypts a block, leaving blk unchanged.
+func (e *tntKeyed) encrypt(blk []byte) tntengine.CipherBlock {
+	res := append(tntengine.CipherBlock(nil), blk...)
+	for _, c := range e.engine {
+		res = c.ApplyF(res)
+	}
+	return res
+}
+
+// rand returns a random stream read from the engine, like a tntengine Rand.
+func (e *tntKeyed) rand() *tntRand {
+	return &tntRand{engine: e, idx: tntengine.CipherBlockBytes}
+}
+
+// tntRand reads the random stream of a tntKeyed engine the same way a
+// tntengine Rand does, including the values it chooses from the stream.
+type tntRand struct {
+	engine *tntKeyed
+	idx    int
+	blk    tntengine.CipherBlock
+}
+
+// Read fills the capacity of p with the stream.  As with a tntengine Rand, the
+// next block is encrypted as soon as the current block is used up.
+func (r *tntRand) Read(p []byte) (int, error) {
+	if r.blk == nil {
+		r.blk = r.engine.key.XORKeyStream(r.engine.cntrKey)
+	}
+	p = p[:0]
+	for {
+		if r.idx >= tntengine.CipherBlockBytes {
+			r.blk = r.engine.encrypt(r.blk)
+			r.idx = 0
+		}
+		left, remaining := len(r.blk)-r.idx, cap(p)-len(p)
+		if remaining < left {
+			p = append(p, r.blk[r.idx:r.idx+remaining]...)
+			r.idx += remaining
+			break
+		}
+		p = append(p, r.blk[r.idx:]...)
+		r.idx += left
+	}
+	return len(p), nil
+}
+
+// int63n returns a value in [0, max) as tntengine's Rand.Int63n does.
+func (r *tntRand) int63n(max int64) int64 {
+	n := max - 1
+	bitLen := bits.Len64(uint64(n))
+	if bitLen == 0 {
+		return n
+	}
+	b := uint(bitLen % 8)
+	if b == 0 {
+		b = 8
+	}
+	buf := make([]byte, (bitLen+7)/8)
+	for {
+		r.Read(buf)
+		buf[0] &= uint8(int(1<<b) - 1)
+		n = 0
+		for _, v := range buf {
+			n = (n << 8) | int64(v)
+		}
+		if n < max {
+			return n
+		}
+	}
+}
+
+// perm returns a permutation of [0, n) as tntengine's Rand.Perm does.
+func (r *tntRand) perm(n int) []int {
+	res := make([]int, n)
+	for i := range res {
+		res[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j := int(r.int63n(int64(i)))
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
+}
+
+// updateRotor updates the rotor from the stream as tntengine's Rotor.Update
+// does.  Only whole blocks are read, so the end of a rotor may keep its
+// proforma bits.
+func (r *tntRand) updateRotor(rotor *tntengine.Rotor) {
+	start := int(r.int63n(int64(rotor.Size)))
+	rotor.Step = int(r.int63n(int64(rotor.Size-1))) + 1
+	r.idx = tntengine.CipherBlockBytes
+	blk := make([]byte, tntengine.CipherBlockBytes)
+	j := 0
+	for range (rotor.Size + 7) / tntengine.CipherBlockSize {
+		r.Read(blk)
+		copy(rotor.Rotor[j:], blk)
+		j += tntengine.CipherBlockBytes
+	}
+	*rotor = *new(tntengine.Rotor).New(rotor.Size, start, rotor.Step, rotor.Rotor)
+}
+
+// updatePermutator updates the permutator from the stream as tntengine's
+// Permutator.Update does.
+func (r *tntRand) updatePermutator(p *tntengine.Permutator) {
+	randp := make([]byte, tntengine.CipherBlockSize)
+	for i, v := range r.perm(tntengine.CipherBlockSize) {
+		randp[i] = byte(v)
+	}
+	*p = *new(tntengine.Permutator).New(tntengine.CipherBlockSize, randp)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/bgallie/tntengine"
+)
+
+// TestTntKeyedMatchesEngine keys tntengine's own proforma machine, copied to
+// testdata/tntengine-proforma.json, both with tntengine and with tntKeyed, and
+// checks that the ciphertext and the random stream are the same.
+func TestTntKeyedMatchesEngine(t *testing.T) {
+	m, err := loadProFormaFile(filepath.Join("testdata", "tntengine-proforma.json"), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{katKey, "secret", "a much longer passphrase with spaces in it"} {
+		var engine tntengine.TntEngine
+		engine.Init([]byte(secret))
+		engine.SetIndex(big.NewInt(0))
+		engine.SetEngineType("E")
+		engine.BuildCipherMachine()
+		plain := katPlaintext()
+		var want []byte
+		for i := 0; i < len(plain); i += tntengine.CipherBlockBytes {
+			engine.Left() <- append(tntengine.CipherBlock(nil), plain[i:i+tntengine.CipherBlockBytes]...)
+			want = append(want, <-engine.Right()...)
+		}
+		engine.CloseCipherMachine()
+
+		e, err := newTntKeyed(m, []byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.setIndex(0)
+		var got []byte
+		for i := 0; i < len(plain); i += tntengine.CipherBlockBytes {
+			got = append(got, e.encrypt(plain[i:i+tntengine.CipherBlockBytes])...)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%q: ciphertext = %x, want %x", secret, got, want)
+		}
+
+		engine.Init([]byte(secret))
+		engine.SetEngineType("E")
+		engine.BuildCipherMachine()
+		want = make([]byte, 1000)
+		new(tntengine.Rand).New(&engine).Read(want)
+		engine.CloseCipherMachine()
+		if e, err = newTntKeyed(m, []byte(secret)); err != nil {
+			t.Fatal(err)
+		}
+		got = make([]byte, 1000)
+		e.rand().Read(got)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%q: stream = %x, want %x", secret, got[:64], want[:64])
+		}
+	}
+}
+
+// TestKatVector checks the known-answer tests of a generated machine.
+func TestKatVector(t *testing.T) {
+	m, err := generateMachine(NewStreamSource(newHmacStream([]byte("kat")).Read), engineLayout, rotorSizes, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kat, err := newKatVector(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := newKatVector(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *kat != *again {
+		t.Errorf("the known-answer tests of the same machine differ")
+	}
+	ct, _ := hex.DecodeString(kat.Ciphertext)
+	stream, _ := hex.DecodeString(kat.Stream)
+	if len(ct) != katBlocks*tntengine.CipherBlockBytes || len(stream) != katStreamLen {
+		t.Errorf("got %d bytes of ciphertext and %d of stream, want %d and %d",
+			len(ct), len(stream), katBlocks*tntengine.CipherBlockBytes, katStreamLen)
+	}
+	if kat.Plaintext == kat.Ciphertext {
+		t.Errorf("the plaintext was not encrypted")
+	}
+
+	other, err := generateMachine(NewStreamSource(newHmacStream([]byte("other")).Read), engineLayout, rotorSizes, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKat, err := newKatVector(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherKat.Ciphertext == kat.Ciphertext || otherKat.Stream == kat.Stream {
+		t.Errorf("different machines have the same known-answer tests")
+	}
+}
@@ -0,0 +1,506 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// inputTypes lists the output types that can be read back into a machine.
+var inputTypes = []string{"json", "ikm"}
+
+// loadProFormaFile reads the proforma machine from the named file ("-" for
+// stdin).  If iType is empty, the type is determined from the file name.  If
+// name is a directory, the ikm definitions are read from the Go files in it.
+func loadProFormaFile(name, iType string) (*Machine, error) {
+	if fi, err := os.Stat(name); err == nil && fi.IsDir() {
+		if len(iType) != 0 && iType != "ikm" {
+			return nil, fmt.Errorf("%s is a directory, only ikm proforma machines can be read from a directory", name)
+		}
+		m, err := loadIkmDir(name)
+		if err == nil {
+			err = m.check()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return m, nil
+	}
+	if len(iType) == 0 {
+		iType = typeFromFileName(name)
+		if len(iType) == 0 {
+			return nil, fmt.Errorf("cannot determine the proforma type of %s", name)
+		}
+	}
+	var err error
+	in := os.Stdin
+	if name != "-" {
+		in, err = os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer in.Close()
+	}
+	m, err := loadProForma(in, iType)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// typeFromFileName returns the output type matching the extension of the
+// file name, or an empty string if there is no match.
+func typeFromFileName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return "json"
+	case ".go":
+		return "ikm"
+	case ".h":
+		return "c"
+	case ".rs":
+		return "rust"
+	case ".py":
+		return "python"
+	}
+	return ""
+}
+
+// loadProForma reads a proforma machine formatted as the given type from r.
+func loadProForma(r io.Reader, iType string) (*Machine, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var m *Machine
+	switch iType {
+	case "json":
+		m, err = loadJSON(src)
+	case "ikm":
+		m, err = loadIkm(src)
+	default:
+		return nil, fmt.Errorf("reading %s proforma machines is not supported", iType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// loadJSON decodes a machine in the format written by the json output type:
+// an array of rotors and permutators in engine order.
+func loadJSON(src []byte) (*Machine, error) {
+	var components []map[string]json.RawMessage
+	if err := json.Unmarshal(src, &components); err != nil {
+		return nil, err
+	}
+	m := new(Machine)
+	for i, c := range components {
+		if _, ok := c["Randp"]; ok {
+			p := new(Permutator)
+			if err := unmarshalComponent(c, p); err != nil {
+				return nil, fmt.Errorf("component %d: %w", i+1, err)
+			}
+			m.Permutators = append(m.Permutators, p)
+			m.Layout += "p"
+		} else if _, ok := c["Rotor"]; ok {
+			r := new(Rotor)
+			if err := unmarshalComponent(c, r); err != nil {
+				return nil, fmt.Errorf("component %d: %w", i+1, err)
+			}
+			m.Rotors = append(m.Rotors, r)
+			m.Layout += "r"
+		} else {
+			return nil, fmt.Errorf("component %d is neither a rotor nor a permutator", i+1)
+		}
+	}
+	return m, nil
+}
+
+// unmarshalComponent decodes the fields of a rotor or permutator.
+func unmarshalComponent(fields map[string]json.RawMessage, v any) error {
+	src, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(src, v)
+}
+
+// loadIkm extracts the proformaRotors and proformaPermutator definitions
+// from Go source.  The source can be the output of the ikm output type or a
+// complete Go file, such as ikmachine's machine.go.
+func loadIkm(src []byte) (*Machine, error) {
+	l := newIkmLoader()
+	if err := l.parse("proforma.go", src); err != nil {
+		return nil, err
+	}
+	return l.machine()
+}
+
+// loadIkmDir extracts the proformaRotors and proformaPermutator definitions
+// from the Go files in dir, such as an ikmachine checkout.
+func loadIkmDir(dir string) (*Machine, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	l := newIkmLoader()
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if err = l.parse(name, src); err != nil {
+			return nil, err
+		}
+	}
+	return l.machine()
+}
+
+// ikmLoader collects the proforma rotor and permutator definitions found in
+// one or more Go source files.
+type ikmLoader struct {
+	fset        *token.FileSet
+	rotors      []*Rotor
+	permutators []*Permutator
+	found       map[string]token.Position // where each definition was found
+}
+
+func newIkmLoader() *ikmLoader {
+	return &ikmLoader{fset: token.NewFileSet(), found: make(map[string]token.Position)}
+}
+
+// parse parses the Go source in src, extracting the proforma definitions.
+func (l *ikmLoader) parse(name string, src []byte) error {
+	f, err := parser.ParseFile(l.fset, name, src, parser.PackageClauseOnly)
+	if err == nil {
+		f, err = parser.ParseFile(l.fset, name, src, parser.SkipObjectResolution)
+	} else {
+		// The ikm output is a set of assignment statements, so wrap it in a
+		// function to make it a valid Go file.  The line directive keeps the
+		// positions in errors those of the snippet.
+		f, err = parser.ParseFile(l.fset, name,
+			"package proforma\nfunc _() {\n//line "+name+":1:1\n"+string(src)+"\n}\n", parser.SkipObjectResolution)
+	}
+	if err != nil {
+		return err
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch d := n.(type) {
+		case *ast.AssignStmt:
+			if len(d.Lhs) == len(d.Rhs) {
+				for i, lhs := range d.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok {
+						if err = l.define(ident, d.Rhs[i]); err != nil {
+							return false
+						}
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			if len(d.Names) == len(d.Values) {
+				for i, ident := range d.Names {
+					if err = l.define(ident, d.Values[i]); err != nil {
+						return false
+					}
+				}
+			}
+		}
+		return true
+	})
+	return err
+}
+
+// define evaluates value if ident names one of the proforma definitions.
+func (l *ikmLoader) define(ident *ast.Ident, value ast.Expr) error {
+	var err error
+	switch ident.Name {
+	case "proformaRotors":
+		l.rotors, err = astRotors(value)
+	case "proformaPermutator":
+		l.permutators, err = astPermutators(value)
+	default:
+		return nil
+	}
+	pos := l.fset.Position(ident.Pos())
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", pos, ident.Name, err)
+	}
+	if prev, ok := l.found[ident.Name]; ok {
+		return fmt.Errorf("%s: %s is already defined at %s", pos, ident.Name, prev)
+	}
+	l.found[ident.Name] = pos
+	return nil
+}
+
+// machine returns the machine made from the definitions found.
+func (l *ikmLoader) machine() (*Machine, error) {
+	if len(l.found) != 2 {
+		return nil, fmt.Errorf("proformaRotors and proformaPermutator must both be defined")
+	}
+	if len(l.rotors) != strings.Count(engineLayout, "r") ||
+		len(l.permutators) != strings.Count(engineLayout, "p") {
+		return nil, fmt.Errorf("%d rotors and %d permutators do not match the engine layout %q",
+			len(l.rotors), len(l.permutators), engineLayout)
+	}
+	return &Machine{Layout: engineLayout, Rotors: l.rotors, Permutators: l.permutators}, nil
+}
+
+// astElements returns the elements of the composite literal expr.
+func astElements(expr ast.Expr) ([]ast.Expr, error) {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		expr = u.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, fmt.Errorf("expected a composite literal")
+	}
+	return lit.Elts, nil
+}
+
+// isCompositeLit reports whether expr is a (possibly &) composite literal.
+func isCompositeLit(expr ast.Expr) bool {
+	_, err := astElements(expr)
+	return err == nil
+}
+
+// astFields returns the fields of the struct composite literal expr, indexed
+// by the lower case field name.  The fields may be keyed or given in the
+// order of the struct's fields, named by order.
+func astFields(expr ast.Expr, order []string) (map[string]ast.Expr, error) {
+	elts, err := astElements(expr)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]ast.Expr, len(elts))
+	for i, e := range elts {
+		kv, ok := e.(*ast.KeyValueExpr)
+		if !ok {
+			if i >= len(order) {
+				return nil, fmt.Errorf("too many fields")
+			}
+			fields[strings.ToLower(order[i])] = e
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("expected a field name")
+		}
+		fields[strings.ToLower(key.Name)] = kv.Value
+	}
+	return fields, nil
+}
+
+// astInt evaluates the constant integer expression expr, making sure it fits
+// in bitSize bits.
+func astInt(expr ast.Expr, bitSize int) (int64, error) {
+	val, err := astConstant(expr)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := constant.Int64Val(constant.ToInt(val))
+	if !ok || v < math.MinInt64>>(64-bitSize) || v > math.MaxInt64>>(64-bitSize) {
+		return 0, fmt.Errorf("%s is not a valid %d bit integer", val, bitSize)
+	}
+	return v, nil
+}
+
+// astConstant evaluates a constant expression made up of literals and the
+// unary + and - operators.
+func astConstant(expr ast.Expr) (constant.Value, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(e.Value, e.Kind, 0), nil
+	case *ast.ParenExpr:
+		return astConstant(e.X)
+	case *ast.UnaryExpr:
+		if e.Op != token.ADD && e.Op != token.SUB {
+			return nil, fmt.Errorf("unsupported operator %s", e.Op)
+		}
+		x, err := astConstant(e.X)
+		if err != nil {
+			return nil, err
+		}
+		switch x.Kind() {
+		case constant.Int, constant.Float, constant.Complex:
+		default:
+			return nil, fmt.Errorf("%s%s is not a numeric constant", e.Op, x)
+		}
+		return constant.UnaryOp(e.Op, x, 0), nil
+	}
+	return nil, fmt.Errorf("expected an integer constant")
+}
+
+// astBytes evaluates a []byte (or [n]byte) composite literal.
+func astBytes(expr ast.Expr) ([]byte, error) {
+	elts, err := astElements(expr)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, len(elts))
+	for i, e := range elts {
+		v, err := astInt(e, 16)
+		if err != nil {
+			return nil, err
+		}
+		if v < 0 || v > 255 {
+			return nil, fmt.Errorf("%d is not a valid byte value", v)
+		}
+		res[i] = byte(v)
+	}
+	return res, nil
+}
+
+// astInt16Fields sets values from the fields with the corresponding names.
+func astInt16Fields(fields map[string]ast.Expr, names []string, values ...*int16) error {
+	for i, name := range names {
+		expr, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("missing field %s", name)
+		}
+		v, err := astInt(expr, 16)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*values[i] = int16(v)
+	}
+	return nil
+}
+
+// astRotors evaluates the proformaRotors composite literal.
+func astRotors(expr ast.Expr) ([]*Rotor, error) {
+	elts, err := astElements(expr)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Rotor, len(elts))
+	for i, e := range elts {
+		fields, err := astFields(e, []string{"size", "start", "step", "current", "rotor"})
+		if err != nil {
+			return nil, fmt.Errorf("rotor %d: %w", i+1, err)
+		}
+		r := new(Rotor)
+		err = astInt16Fields(fields, []string{"size", "start", "step", "current"},
+			&r.Size, &r.Start, &r.Step, &r.Current)
+		if err == nil {
+			if rotor, ok := fields["rotor"]; ok {
+				r.Rotor, err = astBytes(rotor)
+			} else {
+				err = fmt.Errorf("missing field rotor")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rotor %d: %w", i+1, err)
+		}
+		res[i] = r
+	}
+	return res, nil
+}
+
+// astPermutators evaluates the proformaPermutator composite literal, which
+// is either a list of permutators or a single permutator.
+func astPermutators(expr ast.Expr) ([]*Permutator, error) {
+	elts, err := astElements(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(elts) != 0 && !isCompositeLit(elts[0]) {
+		p, err := astPermutator(expr)
+		if err != nil {
+			return nil, err
+		}
+		return []*Permutator{p}, nil
+	}
+	res := make([]*Permutator, len(elts))
+	for i, e := range elts {
+		p, err := astPermutator(e)
+		if err != nil {
+			return nil, fmt.Errorf("permutator %d: %w", i+1, err)
+		}
+		res[i] = p
+	}
+	return res, nil
+}
+
+// astPermutator evaluates a single permutator composite literal.  The bitPerm
+// field is ignored since it is created from randp by the engine.
+func astPermutator(expr ast.Expr) (*Permutator, error) {
+	fields, err := astFields(expr,
+		[]string{"currentState", "maximalStates", "cycles", "randp", "bitPerm"})
+	if err != nil {
+		return nil, err
+	}
+	p := new(Permutator)
+	values := []*int32{&p.CurrentState, &p.MaximalStates}
+	for i, name := range []string{"currentState", "maximalStates"} {
+		e, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("missing field %s", name)
+		}
+		v, err := astInt(e, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		*values[i] = int32(v)
+	}
+	cycles, ok := fields["cycles"]
+	if !ok {
+		return nil, fmt.Errorf("missing field cycles")
+	}
+	elts, err := astElements(cycles)
+	if err != nil {
+		return nil, fmt.Errorf("cycles: %w", err)
+	}
+	p.Cycles = make([]Cycle, len(elts))
+	for i, e := range elts {
+		cFields, err := astFields(e, []string{"start", "length", "current"})
+		if err == nil {
+			c := &p.Cycles[i]
+			err = astInt16Fields(cFields, []string{"start", "length", "current"},
+				&c.Start, &c.Length, &c.Current)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cycle %d: %w", i+1, err)
+		}
+	}
+	randp, ok := fields["randp"]
+	if !ok {
+		return nil, fmt.Errorf("missing field randp")
+	}
+	if p.Randp, err = astBytes(randp); err != nil {
+		return nil, fmt.Errorf("randp: %w", err)
+	}
+	return p, nil
+}
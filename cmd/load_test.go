@@ -0,0 +1,190 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The fuzz targets feed malformed and hostile proforma machines to the
+// loaders and to verify.  They must be rejected with an error, and a machine
+// that is accepted must be usable by every command that reads machines.
+
+// addGoldenSeed adds the named golden file to the seed corpus.
+func addGoldenSeed(f *testing.F, name string) {
+	src, err := os.ReadFile(filepath.Join("testdata", "golden", name))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(src)
+}
+
+// addUnevenSeed adds the golden machine, with one more byte in its second
+// rotor, written as the output type, to the seed corpus.
+func addUnevenSeed(f *testing.F, oType string) {
+	src, err := os.ReadFile(filepath.Join("testdata", "golden", "proforma.json.golden"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	m, err := loadProForma(bytes.NewReader(src), "json")
+	if err != nil {
+		f.Fatal(err)
+	}
+	m.Rotors[1].Rotor = append(m.Rotors[1].Rotor, 0)
+	var buf bytes.Buffer
+	if err = writeProForma(&buf, oType, m); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+}
+
+// useMachine runs a loaded machine through everything that reads machines.
+func useMachine(t *testing.T, m *Machine) {
+	valid := m.verify() == nil
+	m.fingerprintText()
+	// The C and Rust output types need the rotors, and the permutators, to
+	// all be the same size.
+	fixedErr := m.checkFixedSizes()
+	for _, oType := range outputTypes {
+		err := writeProForma(io.Discard, oType, m)
+		if fixedErr != nil && (oType == "c" || oType == "rust") {
+			if err == nil {
+				t.Fatalf("the %s output was written for a machine with components of different sizes: %v", oType, fixedErr)
+			}
+		} else if err != nil {
+			t.Fatalf("writing the %s output: %v", oType, err)
+		}
+	}
+	inspectMachine(io.Discard, m)
+	diffMachines(io.Discard, m, m)
+	pm, err := machineFromPB(machineToPB(m))
+	if err != nil {
+		t.Fatalf("converting the machine to and from protobuf: %v", err)
+	}
+	if pm.fingerprint() != m.fingerprint() {
+		t.Fatalf("the protobuf machine has a different fingerprint")
+	}
+	if !valid {
+		return
+	}
+	// A valid machine must read back from its json output unchanged.
+	var buf bytes.Buffer
+	if err := writeProForma(&buf, "json", m); err != nil {
+		t.Fatal(err)
+	}
+	back, err := loadProForma(&buf, "json")
+	if err != nil {
+		t.Fatalf("reading back the json output: %v", err)
+	}
+	if back.fingerprint() != m.fingerprint() {
+		t.Fatalf("the json output reads back as a different machine")
+	}
+}
+
+func FuzzLoadJSON(f *testing.F) {
+	addGoldenSeed(f, "proforma.json.golden")
+	addUnevenSeed(f, "json")
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`[{"Size":-5,"Start":0,"Step":1,"Current":0,"Rotor":""},{"Randp":"AAE=","Cycles":[]}]`))
+	f.Add([]byte(`[{"Size":32767,"Rotor":"AA=="},{"Randp":null,"Cycles":[{"Start":0,"Length":-1}]}]`))
+	f.Fuzz(func(t *testing.T, src []byte) {
+		m, err := loadProForma(bytes.NewReader(src), "json")
+		if err != nil {
+			return
+		}
+		useMachine(t, m)
+	})
+}
+
+func FuzzLoadIkm(f *testing.F) {
+	addGoldenSeed(f, "proforma.ikm.golden")
+	addUnevenSeed(f, "ikm")
+	f.Add([]byte(`proformaRotors = []*Rotor{{size: -1, start: 0, step: 0, current: 0, rotor: []byte{}}}`))
+	f.Add([]byte("package p\nvar proformaRotors, proformaPermutator = []*Rotor{}, &Permutator{}\n"))
+	f.Add([]byte(`proformaPermutator = &Permutator{cycles: []Cycle{{start: 300, length: 0}}, randp: []byte{1, 2}}`))
+	f.Add([]byte(`proformaRotors = []*Rotor{{size: -"x", start: +'a', step: -(-true), current: 0, rotor: []byte{}}}`))
+	f.Fuzz(func(t *testing.T, src []byte) {
+		m, err := loadProForma(bytes.NewReader(src), "ikm")
+		if err != nil {
+			return
+		}
+		useMachine(t, m)
+	})
+}
+
+func FuzzVerify(f *testing.F) {
+	m, err := generateMachine(goldenSource(), "rp", rotorSizes, cycleSizes)
+	if err != nil {
+		f.Fatal(err)
+	}
+	r, p := m.Rotors[0], m.Permutators[0]
+	c := p.Cycles[0]
+	f.Add(r.Size, r.Start, r.Step, r.Current, r.Rotor, c.Start, c.Length, c.Current, p.CurrentState, p.MaximalStates, p.Randp)
+	f.Add(int16(-8), int16(-1), int16(0), int16(-32768), []byte{}, int16(-1), int16(0), int16(0), int32(-1), int32(0), []byte{})
+	f.Add(int16(32767), int16(32766), int16(1), int16(0), make([]byte, 300), int16(0), int16(256), int16(255), int32(0), int32(256), make([]byte, 255))
+	f.Fuzz(func(t *testing.T, size, start, step, current int16, rotor []byte,
+		cStart, cLength, cCurrent int16, state, maxStates int32, randp []byte) {
+		r := &Rotor{Size: size, Start: start, Step: step, Current: current, Rotor: rotor}
+		p := &Permutator{
+			CurrentState:  state,
+			MaximalStates: maxStates,
+			Cycles:        []Cycle{{Start: cStart, Length: cLength, Current: cCurrent}},
+			Randp:         randp,
+		}
+		// The Go source of the components is written without checking them.
+		_ = r.String()
+		_ = p.String()
+		m := &Machine{Layout: "rp", Rotors: []*Rotor{r}, Permutators: []*Permutator{p}}
+		err := m.verify()
+		// The verify API must agree with verify about the machine.
+		var buf bytes.Buffer
+		if wErr := writeProForma(&buf, "json", m); wErr != nil {
+			t.Fatal(wErr)
+		}
+		if res, status := verifyReader(&buf, "json"); res.Valid != (err == nil) || (status == http.StatusOK) != (err == nil) {
+			t.Fatalf("the verify API returned %d %v, verify returned %v", status, res, err)
+		}
+		if err == nil {
+			useMachine(t, m)
+		}
+	})
+}
+
+// TestLoadIkmPositions checks that the errors in an ikm snippet give the
+// line and column in the snippet, not in the file it is wrapped in.
+func TestLoadIkmPositions(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want string
+	}{
+		{"proformaRotors = = 1\n", "proforma.go:1:18"},
+		{"\n\n\tproformaRotors = []*Rotor{\n\t\t{size: \"x\"},\n\t}\n", "proforma.go:3:2"},
+	} {
+		_, err := loadProForma(strings.NewReader(tc.src), "ikm")
+		if err == nil {
+			t.Fatalf("%q was loaded", tc.src)
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: the error %q is not at %s", tc.src, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// engineLayout is the order of the rotors ('r') and permutators ('p') in the
+// proforma machine used by the engines.
+const engineLayout = "rrprrprr"
+
+// Machine is a proforma machine made up of rotors and permutators.
+type Machine struct {
+	Layout      string        // 'r' for a rotor and 'p' for a permutator, in engine order.
+	Rotors      []*Rotor      // The rotors in the order they appear in Layout.
+	Permutators []*Permutator // The permutators in the order they appear in Layout.
+}
+
+// components returns the rotors and permutators of the machine in the order
+// given by the machine's layout.
+func (m *Machine) components() []any {
+	res := make([]any, 0, len(m.Layout))
+	rIdx, pIdx := 0, 0
+	for _, v := range m.Layout {
+		switch v {
+		case 'r':
+			res = append(res, m.Rotors[rIdx])
+			rIdx++
+		case 'p':
+			res = append(res, m.Permutators[pIdx])
+			pIdx++
+		}
+	}
+	return res
+}
+
+// check makes sure that the machine is well formed enough to be formatted by
+// any of the output types.
+func (m *Machine) check() error {
+	if strings.Count(m.Layout, "r") != len(m.Rotors) ||
+		strings.Count(m.Layout, "p") != len(m.Permutators) ||
+		len(m.Layout) != len(m.Rotors)+len(m.Permutators) {
+		return fmt.Errorf("layout %q does not match %d rotors and %d permutators",
+			m.Layout, len(m.Rotors), len(m.Permutators))
+	}
+	if len(m.Rotors) == 0 || len(m.Permutators) == 0 {
+		return fmt.Errorf("a machine needs at least one rotor and one permutator")
+	}
+	for i, r := range m.Rotors {
+		if r.Size <= 0 || len(r.Rotor) < (int(r.Size)+7)/8+32 {
+			return fmt.Errorf("rotor %d: %d bytes cannot hold %d bits plus a 256 bit slice",
+				i+1, len(r.Rotor), r.Size)
+		}
+	}
+	for i, p := range m.Permutators {
+		if len(p.Randp) != 256 {
+			return fmt.Errorf("permutator %d: randp has %d entries instead of 256", i+1, len(p.Randp))
+		}
+		if len(p.Cycles) == 0 {
+			return fmt.Errorf("permutator %d: no cycles defined", i+1)
+		}
+	}
+	return nil
+}
+
+// checkLayout makes sure that a machine with the given layout can be
+// generated: it may only contain rotors ('r') and permutators ('p'), needs at
+// least one of each, and can have no more rotors than there are rotor sizes.
+func checkLayout(layout string, rotors []int16) error {
+	if strings.Trim(layout, "rp") != "" {
+		return fmt.Errorf("layout %q may only contain 'r' and 'p'", layout)
+	}
+	rCnt, pCnt := strings.Count(layout, "r"), strings.Count(layout, "p")
+	if rCnt == 0 || pCnt == 0 {
+		return fmt.Errorf("layout %q needs at least one rotor and one permutator", layout)
+	}
+	if rCnt > len(rotors) {
+		return fmt.Errorf("layout %q has %d rotors, the most allowed is %d", layout, rCnt, len(rotors))
+	}
+	return nil
+}
+
+// checkFixedSizes makes sure that all of the rotors have as many bytes as the
+// first rotor and all of the permutators as many cycles as the first
+// permutator.  The C and Rust output types declare them as arrays of one size.
+func (m *Machine) checkFixedSizes() error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	for i, r := range m.Rotors {
+		if len(r.Rotor) != len(m.Rotors[0].Rotor) {
+			return fmt.Errorf("rotor %d has %d bytes and rotor 1 has %d, the rotors must all be the same size",
+				i+1, len(r.Rotor), len(m.Rotors[0].Rotor))
+		}
+	}
+	for i, p := range m.Permutators {
+		if len(p.Cycles) != len(m.Permutators[0].Cycles) {
+			return fmt.Errorf("permutator %d has %d cycles and permutator 1 has %d, the permutators must all have the same number of cycles",
+				i+1, len(p.Cycles), len(m.Permutators[0].Cycles))
+		}
+	}
+	return nil
+}
+
+// verify makes sure that the machine is a valid proforma machine: it is well
+// formed, the rotor positions are in range and each rotor ends with a copy of
+// its first 256 bits, and the permutator cycles cover all 256 values and randp
+// is a permutation.  All of the problems found are returned.
+func (m *Machine) verify() error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	var errs []error
+	for i, r := range m.Rotors {
+		for _, err := range r.problems() {
+			errs = append(errs, fmt.Errorf("rotor %d: %w", i+1, err))
+		}
+	}
+	for i, p := range m.Permutators {
+		for _, err := range p.problems() {
+			errs = append(errs, fmt.Errorf("permutator %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// problems returns the problems found with the rotor.
+func (r *Rotor) problems() []error {
+	var errs []error
+	if r.Start < 0 || r.Start >= r.Size {
+		errs = append(errs, fmt.Errorf("start %d is not in [0, %d)", r.Start, r.Size))
+	}
+	if r.Step < 1 || r.Step >= r.Size {
+		errs = append(errs, fmt.Errorf("step %d is not in [1, %d)", r.Step, r.Size))
+	}
+	if r.Current < 0 || r.Current >= r.Size {
+		errs = append(errs, fmt.Errorf("current %d is not in [0, %d)", r.Current, r.Size))
+	}
+	bit := func(i int) byte { return (r.Rotor[i>>3] >> (i & 7)) & 1 }
+	for i := range 256 {
+		if bit(i) != bit(int(r.Size)+i) {
+			errs = append(errs, fmt.Errorf("bit %d does not match bit %d of the 256 bit slice", int(r.Size)+i, i))
+			break
+		}
+	}
+	return errs
+}
+
+// problems returns the problems found with the permutator.
+func (p *Permutator) problems() []error {
+	var errs []error
+	next := 0
+	maximalStates := int64(1)
+	for i, c := range p.Cycles {
+		if int(c.Start) != next {
+			errs = append(errs, fmt.Errorf("cycle %d starts at %d instead of %d", i+1, c.Start, next))
+		}
+		if c.Length <= 0 {
+			errs = append(errs, fmt.Errorf("cycle %d has length %d", i+1, c.Length))
+		} else {
+			if c.Current < 0 || c.Current >= c.Length {
+				errs = append(errs, fmt.Errorf("cycle %d: current %d is not in [0, %d)", i+1, c.Current, c.Length))
+			}
+			// Stop once the product cannot be held by maximalStates.
+			if maximalStates <= math.MaxInt32 {
+				maximalStates *= int64(c.Length)
+			}
+		}
+		next = int(c.Start) + int(c.Length)
+	}
+	if next != 256 {
+		errs = append(errs, fmt.Errorf("the cycles cover %d values instead of 256", next))
+	}
+	if int64(p.MaximalStates) != maximalStates {
+		errs = append(errs, fmt.Errorf("maximalStates is %d instead of %d", p.MaximalStates, maximalStates))
+	}
+	if p.CurrentState < 0 || p.CurrentState >= p.MaximalStates {
+		errs = append(errs, fmt.Errorf("currentState %d is not in [0, %d)", p.CurrentState, p.MaximalStates))
+	}
+	var seen [256]bool
+	for _, v := range p.Randp {
+		if seen[v] {
+			errs = append(errs, fmt.Errorf("randp is not a permutation: %d appears more than once", v))
+			break
+		}
+		seen[v] = true
+	}
+	return errs
+}
+
+// writeProForma writes the machine to w formatted as the given output type.
+func writeProForma(w io.Writer, oType string, m *Machine) error {
+	var err error
+	switch oType {
+	case "json":
+		jEncoder := json.NewEncoder(w)
+		jEncoder.SetEscapeHTML(false)
+		err = jEncoder.Encode(m.components())
+	case "c":
+		var src string
+		if src, err = cSource(m); err == nil {
+			_, err = fmt.Fprint(w, src)
+		}
+	case "rust":
+		var src string
+		if src, err = rustSource(m); err == nil {
+			_, err = fmt.Fprint(w, src)
+		}
+	case "python":
+		_, err = fmt.Fprint(w, pythonSource(m))
+	default:
+		_, err = fmt.Fprint(w, ikmSource(m))
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mixTntengine bool
+	mixFiles     []string
+	mixBytes     int64
+)
+
+// mixSalt is the HMAC key used to extract the seed from the mixed sources.
+const mixSalt = "genProforma mixed v1"
+
+// mixedCmd represents the mixed command
+var mixedCmd = &cobra.Command{
+	Use:   "mixed [passphrase]",
+	Short: "Generate a new proforma machine from a mix of entropy sources",
+	Long: `Generate a new proforma machine using a mix of entropy sources.  Data from
+Go's cryptographically secure random number generator, and optionally a keyed
+tntengine stream and the contents of files (such as recorded dice rolls or a
+hardware random number generator), are combined with HMAC-SHA256 into a seed
+for a HMAC-SHA256 based generator.  No single source determines the machine
+as long as one of the sources is unpredictable.`,
+	Annotations: map[string]string{sourceAnnotation: "mixed"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCommand(cmd, args, "json")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mixedCmd)
+	mixedCmd.Flags().BoolVar(&mixTntengine, "tntengine", false, "mix in data from a tntengine keyed with the passphrase")
+	mixedCmd.Flags().StringArrayVar(&mixFiles, "entropy-file", nil, "mix in the contents of this file (may be repeated)")
+	mixedCmd.Flags().Int64Var(&mixBytes, "entropy-bytes", 1<<20, "maximum number of bytes to read from each entropy file")
+	RegisterSource("mixed", newMixedSource)
+}
+
+// newMixedSource returns a Source seeded with a mix of the entropy sources.
+func newMixedSource(args []string) (Source, error) {
+	extract := hmac.New(sha256.New, []byte(mixSalt))
+	sources := []string{"crypto/rand"}
+	data := make([]byte, 64)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		return nil, err
+	}
+	mixInput(extract, "crypto/rand", data)
+	if mixTntengine {
+		tnt, err := newSource("tntengine", args)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tnt.Read(data)
+		closeSource(tnt)
+		if err != nil {
+			return nil, err
+		}
+		mixInput(extract, "tntengine", data)
+		sources = append(sources, "tntengine")
+	}
+	for _, name := range mixFiles {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(f, mixBytes))
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("%s: no entropy data", name)
+		}
+		mixInput(extract, "file", data)
+		sources = append(sources, name)
+	}
+	fmt.Fprintln(os.Stderr, "Mixed entropy from:", strings.Join(sources, ", "))
+	return NewStreamSource(newHmacStream(extract.Sum(nil)).Read), nil
+}
+
+// mixInput adds the data from the named source to the extractor.  The name
+// and data are length prefixed so that the inputs cannot run together.
+func mixInput(extract hash.Hash, name string, data []byte) {
+	extract.Write(binary.BigEndian.AppendUint32(nil, uint32(len(name))))
+	extract.Write([]byte(name))
+	extract.Write(binary.BigEndian.AppendUint64(nil, uint64(len(data))))
+	extract.Write(data)
+}
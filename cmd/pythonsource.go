@@ -0,0 +1,96 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// pythonSource formats the rotors and permutators as a Python module.  The
+// rotors and permutators are defined as dataclass instances with the same
+// fields as the Rotor, Cycle and Permutator types, with the byte slices
+// given as bytes literals.
+func pythonSource(m *Machine) string {
+	var output bytes.Buffer
+	rotors, permutators := m.Rotors, m.Permutators
+	output.WriteString("# Code generated by genProforma. DO NOT EDIT.\n")
+	output.WriteString(m.fingerprintComment("# "))
+	output.WriteString("\"\"\"Proforma rotors and permutators generated by genProforma.\"\"\"\n\n")
+	output.WriteString("from dataclasses import dataclass\n\n\n")
+	output.WriteString("@dataclass(frozen=True)\n" +
+		"class Rotor:\n" +
+		"    size: int  # the size in bits for this rotor\n" +
+		"    start: int  # the initial starting position of the rotor\n" +
+		"    step: int  # the step size in bits for this rotor\n" +
+		"    current: int  # the current position of this rotor\n" +
+		"    rotor: bytes  # the rotor\n\n\n")
+	output.WriteString("@dataclass(frozen=True)\n" +
+		"class Cycle:\n" +
+		"    start: int  # The starting point (into randp) for this cycle.\n" +
+		"    length: int  # The length of the cycle.\n" +
+		"    current: int  # The point in the cycle [0 .. cycles.length-1] to start\n\n\n")
+	output.WriteString("@dataclass(frozen=True)\n" +
+		"class Permutator:\n" +
+		"    currentState: int  # Current number of cycles for this permutator.\n" +
+		"    maximalStates: int  # Maximum number of cycles before repeating.\n" +
+		"    cycles: tuple[Cycle, ...]  # Cycles ordered by the current permutation.\n" +
+		"    randp: bytes  # Values 0 - 255 in a random order.\n" +
+		"    bitPerm: bytes  # Permutation table created from randp.\n\n\n")
+	output.WriteString("PROFORMA_ROTORS = (\n")
+	for _, r := range rotors {
+		output.WriteString("    Rotor(\n")
+		output.WriteString(fmt.Sprintf("        size=%d,\n", r.Size))
+		output.WriteString(fmt.Sprintf("        start=%d,\n", r.Start))
+		output.WriteString(fmt.Sprintf("        step=%d,\n", r.Step))
+		output.WriteString(fmt.Sprintf("        current=%d,\n", r.Current))
+		output.WriteString("        rotor=(\n")
+		writePythonBytes(&output, "            ", r.Rotor)
+		output.WriteString("        ),\n    ),\n")
+	}
+	output.WriteString(")\n\n")
+	output.WriteString("PROFORMA_PERMUTATORS = (\n")
+	for _, p := range permutators {
+		output.WriteString("    Permutator(\n")
+		output.WriteString(fmt.Sprintf("        currentState=%d,\n", p.CurrentState))
+		output.WriteString(fmt.Sprintf("        maximalStates=%d,\n", p.MaximalStates))
+		output.WriteString("        cycles=(\n")
+		for _, c := range p.Cycles {
+			output.WriteString(fmt.Sprintf("            Cycle(start=%d, length=%d, current=%d),\n",
+				c.Start, c.Length, c.Current))
+		}
+		output.WriteString("        ),\n        randp=(\n")
+		writePythonBytes(&output, "            ", p.Randp)
+		output.WriteString("        ),\n        bitPerm=(\n")
+		writePythonBytes(&output, "            ", p.bitPerm[:])
+		output.WriteString("        ),\n    ),\n")
+	}
+	output.WriteString(")\n")
+	return output.String()
+}
+
+// writePythonBytes writes data to output as a sequence of bytes literals of
+// (up to) 16 bytes, each line starting with indent.  Python concatenates the
+// adjacent literals into a single bytes value.
+func writePythonBytes(output *bytes.Buffer, indent string, data []byte) {
+	for i := 0; i < len(data); i += 16 {
+		output.WriteString(indent + "b\"")
+		for _, k := range data[i:min(i+16, len(data))] {
+			output.WriteString(fmt.Sprintf("\\x%02x", k))
+		}
+		output.WriteString("\"\n")
+	}
+}
@@ -17,7 +17,6 @@ package cmd
 
 import (
 	"crypto/rand"
-	"math/big"
 
 	"github.com/spf13/cobra"
 )
@@ -26,30 +25,20 @@ import (
 var randomCmd = &cobra.Command{
 	Use:   "random",
 	Short: "Generate a new proforma machine",
-	Long:  `Generate a new proforma machine using Go's cryptographically secure random number generator.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if rootCmd.Flags().Changed("outputType") {
-			if outputType != "json" && outputType != "ikm" {
-				cobra.CheckErr(outputType + " is not a valid output type.")
-			}
-		} else {
-			rootCmd.Flags().Set("outputType", "json")
-		}
-		generateProForma(outputType)
+	Long: `Generate a new proforma machine using Go's cryptographically secure random number generator.
+
+The rotor and permutator values are chosen from the random bytes with
+rejection sampling, so they are uniformly distributed.`,
+	Annotations: map[string]string{sourceAnnotation: "random"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCommand(cmd, args, "json")
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(randomCmd)
-	// Define rRead and rInt to use the crypto/rand based functions.
-	rRead = rand.Read
-	rPerm = perm
-	rInt = cInt
-}
-
-func cInt(n int64) int64 {
-	max := big.NewInt(n)
-	j, err := rand.Int(rand.Reader, max)
-	cobra.CheckErr(err)
-	return j.Int64()
+	RegisterSource("random", func([]string) (Source, error) {
+		// crypto/rand is safe for concurrent use.
+		return NewConcurrentStreamSource(rand.Read), nil
+	})
 }
@@ -17,9 +17,10 @@ package cmd
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -31,14 +32,44 @@ var rootCmd = &cobra.Command{
 	Short: "Generate proforma rotors and permutators.",
 	Long: `genProfroma is a tool to generates a set of rotors and permutators that 
 	can be used to override the builtin proforma rotors
-	and permutators.`,
-	Version: Version,
+	and permutators.
+
+Exit status:
+  0  success
+  1  an error without a more specific code
+  2  the command line is not valid: an unknown command or flag, a bad flag
+     value or the wrong number of arguments
+  3  no passphrase was given, or the shares do not rebuild the master seed
+  4  the source of random data failed, ran out of data or failed its health
+     tests
+  5  the machine, test vectors, manifest or shares could not be written
+  6  a proforma machine could not be read or is not valid
+  7  the machine failed the --cross-check
+  8  diff found differences between the machines
+
+With --json-errors an error is written to stderr as a single line of JSON,
+such as (wrapped here):
+  {"error":"starting the tntengine source: you must supply a password",
+   "kind":"secret","code":3,"command":"genProforma tntengine"}
+The kind is one of "error", "usage", "secret", "entropy", "output", "input" or
+"check" or "differ", following the exit status.`,
+	Version:       Version,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandStarted = true
+		return configErr
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The error, if any, is reported on stderr and genProforma exits with the
+// exit status for it.
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	if code := run(os.Args[1:], os.Stderr); code != exitOK {
+		os.Exit(code)
+	}
 }
 
 func init() {
@@ -46,12 +77,44 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.genProforma.yaml)")
 	rootCmd.PersistentFlags().StringVarP(&outputFileName, "outputfile", "f", "-", "output file to write the proforma rotors and permutators to")
 	rootCmd.PersistentFlags().StringVarP(&outputType, "outputType", "t", "-", `Output type to generate.
-	The valid types are "json" (default) and "ikm" (default for ikmachine command).
-	    json: outputs JSON encoded format.
-	    ikm: outputs a string in valid golang that can replace the proforma rotors and permutators in ikmachine/machine.go`)
+	The valid types are "json" (default), "ikm" (default for ikmachine command), "c", "rust" and "python".
+	    json: outputs JSON encoded format.  The fingerprint, which has no place in the JSON,
+	          is written to a file named like the output file with the extension ".meta.json".
+	    ikm: outputs a string in valid golang that can replace the proforma rotors and permutators in ikmachine/machine.go
+	    c: outputs a C header file defining the proforma rotors and permutators as static const structs.
+	    rust: outputs a Rust source file defining the proforma rotors and permutators as static arrays.
+	    python: outputs a Python module defining the proforma rotors and permutators as dataclass instances.`)
+}
+
+// checkOutputType verifies that the output type given on the command line is
+// valid, setting it to defaultType if the output type was not given.
+func checkOutputType(defaultType string) error {
+	if rootCmd.Flags().Changed("outputType") {
+		if !slices.Contains(outputTypes, outputType) {
+			return withExitCode(exitUsage, fmt.Errorf("%s is not a valid output type", outputType))
+		}
+	} else {
+		rootCmd.Flags().Set("outputType", defaultType)
+	}
+	return nil
+}
+
+// generateCommand is run by the commands that generate proforma machines.  It
+// checks the output type, setting it to defaultType if it was not given, binds
+// the command's source and generates the machines.
+func generateCommand(cmd *cobra.Command, args []string, defaultType string) error {
+	if err := checkOutputType(defaultType); err != nil {
+		return err
+	}
+	if err := bindSource(cmd, args); err != nil {
+		return err
+	}
+	defer closeSource(source)
+	return generateProForma(outputType)
 }
 
-// initConfig reads in config file and ENV variables if set.
+// initConfig reads in config file and ENV variables if set.  An error is
+// kept in configErr and returned when the command is run.
 func initConfig() {
 	if cfgFile != "" {
 		// Use config file from the flag.
@@ -59,7 +122,10 @@ func initConfig() {
 	} else {
 		// Find home directory.
 		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
+		if err != nil {
+			configErr = fmt.Errorf("finding the config file: %w", err)
+			return
+		}
 
 		// Search config in home directory with name ".genProforma" (without extension).
 		viper.AddConfigPath(home)
@@ -76,26 +142,18 @@ func initConfig() {
 }
 
 var (
-	cfgFile string
+	cfgFile   string
+	configErr error
 	// rotoSizes is an array of possible rotor sizes.  It consists of prime
 	// numbers less than 1792 to allow for a 256 bit splce at the end of the
 	// rotor and still be less then or equal to 2048 bits (32 bytes).  The rotor
 	// sizes selected from this list will maximizes the number of unique states
 	// the rotors can take.
-	rotorSizes      = []int16{1789, 1787, 1777, 1759, 1753, 1747}
-	cycleSizes      = CycleSizes{61, 63, 65, 67}
-	outputFileName  string
-	rotors          = []*Rotor{new(Rotor), new(Rotor), new(Rotor), new(Rotor), new(Rotor), new(Rotor)}
-	permutators     = []*Permutator{new(Permutator), new(Permutator)}
-	proformaMachine = make([]any, len(rotors)+len(permutators))
-	rCnt            = 0
-	pCnt            = 0
-	outputFile      *os.File
-	rRead           func([]byte) (n int, err error)
-	rPerm           func(int) []int
-	rInt            func(int64) int64
-	outputType      string
-	prefix          string = ""
+	rotorSizes     = []int16{1789, 1787, 1777, 1759, 1753, 1747}
+	cycleSizes     = CycleSizes{61, 63, 65, 67}
+	outputFileName string
+	outputType     string
+	outputTypes    = []string{"json", "ikm", "c", "rust", "python"}
 )
 
 // CycleSizes contains the cycle sizes used by the permutators.
@@ -112,29 +170,20 @@ type Rotor struct {
 
 // String converts a Rotor to a string representation of the Rotor.
 func (r *Rotor) String() string {
+	return r.goSource("")
+}
+
+// goSource formats the Rotor as a Go composite literal with each line
+// starting with prefix.
+func (r *Rotor) goSource(prefix string) string {
 	var output bytes.Buffer
-	rotorLen := len(r.Rotor)
 	output.WriteString(prefix + "{\n")
 	output.WriteString(fmt.Sprintf("%s\tsize:    %d,\n", prefix, r.Size))
 	output.WriteString(fmt.Sprintf("%s\tstart:   %d,\n", prefix, r.Start))
 	output.WriteString(fmt.Sprintf("%s\tstep:    %d,\n", prefix, r.Step))
 	output.WriteString(fmt.Sprintf("%s\tcurrent: %d,\n", prefix, r.Current))
 	output.WriteString(prefix + "\trotor:   []byte{\n")
-	for i := 0; i < rotorLen; i += 16 {
-		output.WriteString(prefix + "\t\t")
-		if i+16 < rotorLen {
-			for _, k := range r.Rotor[i : i+15] {
-				output.WriteString(fmt.Sprintf("%#02x, ", k))
-			}
-			output.WriteString(fmt.Sprintf("%#02x,\n", r.Rotor[i+15]))
-		} else {
-			l := len(r.Rotor[i:])
-			for _, k := range r.Rotor[i : i+l-1] {
-				output.WriteString(fmt.Sprintf("%#02x, ", k))
-			}
-			output.WriteString(fmt.Sprintf("%#02x}}", r.Rotor[i+l-1]))
-		}
-	}
+	writeGoBytes(&output, prefix+"\t\t", r.Rotor, "}}")
 	return output.String()
 }
 
@@ -158,109 +207,122 @@ type Permutator struct {
 
 // String formats a string representing the permutator (as Go source code).
 func (p *Permutator) String() string {
+	return p.goSource("")
+}
+
+// goSource formats the Permutator as a Go composite literal with each line
+// starting with prefix.
+func (p *Permutator) goSource(prefix string) string {
 	var output bytes.Buffer
 	output.WriteString(prefix + "{\n")
 	output.WriteString(fmt.Sprintf(prefix+"\tcurrentState:  %d,\n", p.CurrentState))
 	output.WriteString(fmt.Sprintf(prefix+"\tmaximalStates: %d,\n", p.MaximalStates))
 	output.WriteString(prefix + "\tcycles: []Cycle{\n")
-	var i int
-	if len(p.Cycles) > 1 {
-		for i = range len(p.Cycles) - 1 {
-			output.WriteString(prefix + "\t\t")
-			output.WriteString(fmt.Sprintf("{start: %d, length: %d, current: %d},\n",
-				p.Cycles[i].Start, p.Cycles[i].Length, p.Cycles[i].Current))
-		}
-		i++ // make i the index of the last permutation cycle.
-	}
-	output.WriteString(prefix + "\t\t")
-	output.WriteString(fmt.Sprintf("{start: %d, length: %d, current: %d},\n",
-		p.Cycles[i].Start, p.Cycles[i].Length, p.Cycles[i].Current))
-	output.WriteString(prefix + "\t},\n" + prefix + "\trandp: []byte{\n")
-	for i := 0; i < 256; i += 16 {
+	for _, c := range p.Cycles {
 		output.WriteString(prefix + "\t\t")
-		if i != (256 - 16) {
-			for _, k := range p.Randp[i : i+15] {
-				output.WriteString(fmt.Sprintf("%#02x, ", k))
-			}
-			output.WriteString(fmt.Sprintf("%#02x,\n", p.Randp[i+15]))
-		} else {
-			for _, k := range p.Randp[i : i+15] {
-				output.WriteString(fmt.Sprintf("%#02x, ", k))
-			}
-			output.WriteString(fmt.Sprintf("%#02x},\n", p.Randp[i+15]))
-		}
+		output.WriteString(fmt.Sprintf("{start: %d, length: %d, current: %d},\n",
+			c.Start, c.Length, c.Current))
 	}
+	output.WriteString(prefix + "\t},\n" + prefix + "\trandp: []byte{\n")
+	writeGoBytes(&output, prefix+"\t\t", p.Randp, "},\n")
 	output.WriteString(prefix + "\tbitPerm: [256]byte{\n")
-	for i := 0; i < 256; i += 16 {
-		output.WriteString(prefix + "\t\t")
-		if i != (256 - 16) {
-			for _, k := range p.bitPerm[i : i+15] {
-				output.WriteString(fmt.Sprintf("%#02x, ", k))
-			}
-			output.WriteString(fmt.Sprintf("%#02x,\n", p.bitPerm[i+15]))
+	writeGoBytes(&output, prefix+"\t\t", p.bitPerm[:], "}}")
+	return output.String()
+}
+
+// writeGoBytes writes data to output as the elements of a Go byte slice
+// literal: lines of (up to) 16 hex bytes, each line starting with indent.  The
+// last line ends with closing instead of a comma.
+func writeGoBytes(output *bytes.Buffer, indent string, data []byte, closing string) {
+	if len(data) == 0 {
+		output.WriteString(indent + closing)
+		return
+	}
+	for i := 0; i < len(data); i += 16 {
+		output.WriteString(indent)
+		line := data[i:min(i+16, len(data))]
+		for _, k := range line[:len(line)-1] {
+			output.WriteString(fmt.Sprintf("%#02x, ", k))
+		}
+		if i+16 < len(data) {
+			output.WriteString(fmt.Sprintf("%#02x,\n", line[len(line)-1]))
 		} else {
-			for _, k := range p.bitPerm[i : i+15] {
-				output.WriteString(fmt.Sprintf("%#02x, ", k))
-			}
-			output.WriteString(fmt.Sprintf("%#02x}}", p.bitPerm[i+15]))
+			output.WriteString(fmt.Sprintf("%#02x%s", line[len(line)-1], closing))
 		}
 	}
-	return output.String()
 }
 
-func perm(n int) []int {
-	if n < 0 {
-		panic(fmt.Sprintf("Perm called with a negative argument [%d]", n))
-	}
-	res := make([]int, n)
-	for i := 1; i < n; i++ {
-		j := rInt(int64(i + 1))
-		res[i] = res[j]
-		res[j] = i
+// writeHexBytes writes data to output as lines of (up to) 16 comma separated
+// hex bytes, each line starting with indent.
+func writeHexBytes(output *bytes.Buffer, indent string, data []byte) {
+	for i := 0; i < len(data); i += 16 {
+		output.WriteString(indent)
+		for j, k := range data[i:min(i+16, len(data))] {
+			if j != 0 {
+				output.WriteString(" ")
+			}
+			output.WriteString(fmt.Sprintf("%#02x,", k))
+		}
+		output.WriteString("\n")
 	}
-	return res
 }
 
-func randP() []byte {
+func randP(s Source) ([]byte, error) {
 	res := make([]byte, 256)
 
 	// Create a table of byte values [0...255] in a random order
-	for i, val := range rPerm(256) {
+	p, err := s.Perm(256)
+	if err != nil {
+		return nil, err
+	}
+	for i, val := range p {
 		res[i] = byte(val)
 	}
 
-	return res
+	return res, nil
 }
 
-func updateRotor(r *Rotor) {
-	r.Size = rotorSizes[rCnt]
-	r.Start = int16(rInt(int64(r.Size)))
-	r.Step = int16(rInt(int64(r.Size-1))) + 1
+func updateRotor(s Source, r *Rotor, size int16) error {
+	r.Size = size
+	start, err := s.Int63n(int64(r.Size))
+	if err != nil {
+		return err
+	}
+	step, err := s.Int63n(int64(r.Size - 1))
+	if err != nil {
+		return err
+	}
+	r.Start = int16(start)
+	r.Step = int16(step) + 1
 	// blkCnt is the total number of bytes needed to hold rotorSize bits + a slice of 256 bits
 	blkCnt := ((r.Size + 7) / 8)
 	r.Rotor = make([]byte, 256)
 	rData := make([]byte, blkCnt)
-	_, err := rRead(rData)
-	cobra.CheckErr(err)
+	if _, err = s.Read(rData); err != nil {
+		return err
+	}
 	copy(r.Rotor, rData)
 	sliceRotor(r)
-	rCnt++
+	return nil
 }
 
-func updatePermutator(p *Permutator) {
-	cycleOrder := perm(len(cycleSizes))
+func updatePermutator(s Source, p *Permutator, cycles CycleSizes) error {
+	cycleOrder, err := s.Perm(len(cycles))
+	if err != nil {
+		return err
+	}
 	p.CurrentState = 0
 	p.MaximalStates = 1
-	p.Cycles = make([]Cycle, len(cycleSizes))
+	p.Cycles = make([]Cycle, len(cycles))
 	runningLength := int16(0)
 	for i := range p.Cycles {
 		p.Cycles[i].Start = runningLength
-		p.Cycles[i].Length = cycleSizes[cycleOrder[i]]
+		p.Cycles[i].Length = cycles[cycleOrder[i]]
 		p.MaximalStates *= int32(p.Cycles[i].Length)
 		runningLength += p.Cycles[i].Length
 	}
-	p.Randp = randP()
-	pCnt++
+	p.Randp, err = randP(s)
+	return err
 }
 
 // sliceRotor appends the first 256 bits of the rotor to the end of the rotor.
@@ -285,53 +347,119 @@ func sliceRotor(r *Rotor) {
 	}
 }
 
-func generateProForma(oType string) {
-	var err error
-	updateRotor(rotors[0])
-	proformaMachine[0] = rotors[0]
-	updateRotor(rotors[1])
-	proformaMachine[1] = rotors[1]
-	updatePermutator(permutators[0])
-	proformaMachine[2] = permutators[0]
-	updateRotor(rotors[2])
-	proformaMachine[3] = rotors[2]
-	updateRotor(rotors[3])
-	proformaMachine[4] = rotors[3]
-	updatePermutator(permutators[1])
-	proformaMachine[5] = permutators[1]
-	updateRotor(rotors[4])
-	proformaMachine[6] = rotors[4]
-	updateRotor(rotors[5])
-	proformaMachine[7] = rotors[5]
-	if len(outputFileName) != 0 {
-		if outputFileName == "-" {
-			outputFile = os.Stdout
-		} else {
-			outputFile, err = os.Create(outputFileName)
-			cobra.CheckErr(err)
+// newMachine generates a new proforma machine with the engine layout from the
+// current source, cross-checking it with tntengine if --cross-check is given.
+func newMachine() (*Machine, error) {
+	m, err := generateMachine(source, engineLayout, rotorSizes, cycleSizes)
+	if err != nil {
+		return nil, err
+	}
+	if crossCheck {
+		if err := engineCheck(m); err != nil {
+			return nil, withExitCode(exitCheck, fmt.Errorf("the machine failed the tntengine cross-check:\n%w", err))
 		}
 	}
+	return m, nil
+}
+
+// generateMachine generates a new proforma machine with the given layout,
+// rotor sizes and permutator cycle sizes from the source s, creating the
+// rotors and permutators in the order they are used in the engine.
+func generateMachine(s Source, layout string, rotors []int16, cycles CycleSizes) (*Machine, error) {
+	if err := checkLayout(layout, rotors); err != nil {
+		return nil, err
+	}
+	m := &Machine{Layout: layout}
+	for _, v := range m.Layout {
+		switch v {
+		case 'r':
+			r := new(Rotor)
+			if err := updateRotor(s, r, rotors[len(m.Rotors)]); err != nil {
+				return nil, withExitCode(exitEntropy, fmt.Errorf("reading entropy for rotor %d: %w", len(m.Rotors)+1, err))
+			}
+			m.Rotors = append(m.Rotors, r)
+		case 'p':
+			p := new(Permutator)
+			if err := updatePermutator(s, p, cycles); err != nil {
+				return nil, withExitCode(exitEntropy, fmt.Errorf("reading entropy for permutator %d: %w", len(m.Permutators)+1, err))
+			}
+			m.Permutators = append(m.Permutators, p)
+		}
+	}
+	return m, nil
+}
 
+// generateProForma generates the machine, or the machines with --count, from
+// the current source and writes them as the output type oType.
+func generateProForma(oType string) error {
+	if source == nil {
+		return errors.New("no source of random data was selected")
+	}
+	if katVectors && count == 1 && (outputFileName == "-" || len(outputFileName) == 0) {
+		return withExitCode(exitUsage, errors.New("--kat needs an output file to write the test vectors next to"))
+	}
+	if katVectors && oType == "ikm" {
+		return errKatIkm
+	}
+	if count != 1 {
+		return generateBatch(oType)
+	}
+	m, err := newMachine()
+	if err != nil {
+		return err
+	}
+	outputFile, err := openOutputFile(outputFileName)
+	if err != nil {
+		return withExitCode(exitOutput, fmt.Errorf("writing the machine: %w", err))
+	}
 	defer outputFile.Close()
-	if oType == "json" {
-		jEncoder := json.NewEncoder(outputFile)
-		jEncoder.SetEscapeHTML(false)
-		err = jEncoder.Encode(proformaMachine)
-	} else {
-		prefix = "\t\t"
-		fmt.Fprint(outputFile,
-			"\tproformaRotors = []*Rotor{\n\t\t// Define the proforma "+
-				"rotors used to create the actual rotors to use.\n")
-		for _, v := range rotors {
-			fmt.Fprintf(outputFile, "%s,\n", v)
+	if err = writeProForma(outputFile, oType, m); err != nil {
+		return withExitCode(exitOutput, fmt.Errorf("writing the machine: %w", err))
+	}
+	if oType == "json" && outputFile != os.Stdout {
+		if err = writeMeta(outputFileName, m); err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("writing the metadata: %w", err))
 		}
-		fmt.Fprint(outputFile, "\t}\n")
-		fmt.Fprint(outputFile,
-			"\tproformaPermutator = &Permutator{\n\t\t// Define the "+
-				"proforma permutator used to create the actual permutator to use.\n")
-		for _, v := range permutators {
-			fmt.Fprintf(outputFile, "%s,\n", v)
+	}
+	fmt.Fprintln(os.Stderr, "Source:     ", sourceName)
+	fmt.Fprintln(os.Stderr, "Fingerprint:", m.fingerprintText())
+	if crossCheck {
+		fmt.Fprintf(os.Stderr, "Cross-check: passed (tntengine, %d blocks)\n", crossCheckBlocks)
+	}
+	if katVectors {
+		if err = writeKat(katFileName(outputFileName), m); err != nil {
+			return withExitCode(exitOutput, fmt.Errorf("writing the test vectors: %w", err))
 		}
-		fmt.Fprint(outputFile, "\t}\n")
+		fmt.Fprintln(os.Stderr, "Test vectors:", katFileName(outputFileName))
 	}
+	return nil
+}
+
+// openOutputFile opens the named output file, using stdout if name is "-".
+func openOutputFile(name string) (*os.File, error) {
+	if name == "-" || len(name) == 0 {
+		return os.Stdout, nil
+	}
+	return os.Create(name)
+}
+
+// ikmSource formats the rotors and permutators as Go source code that can
+// replace the proforma rotors and permutators in ikmachine/machine.go.
+func ikmSource(m *Machine) string {
+	var output bytes.Buffer
+	rotors, permutators := m.Rotors, m.Permutators
+	output.WriteString(m.fingerprintComment("\t// "))
+	output.WriteString("\tproformaRotors = []*Rotor{\n\t\t// Define the proforma " +
+		"rotors used to create the actual rotors to use.\n")
+	for _, v := range rotors {
+		output.WriteString(v.goSource("\t\t") + ",\n")
+	}
+	output.WriteString("\t}\n")
+	output.WriteString("\tproformaPermutator = &Permutator{\n\t\t// Define the " +
+		"proforma permutator used to create the actual permutator to use.\n")
+	for _, v := range permutators {
+		output.WriteString(v.goSource("\t\t") + ",\n")
+	}
+	output.WriteString("\t}\n")
+	return output.String()
 }
@@ -0,0 +1,95 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// maxRotorSize is the largest rotor that, with its 256 bit slice, fits in the
+// 256 bytes of a rotor.
+const maxRotorSize = 2048 - 256
+
+// rotorBit returns bit i of the rotor data.
+func rotorBit(data []byte, i int) byte {
+	return data[i>>3] >> (i & 7) & 1
+}
+
+// checkSliceRotor slices a rotor of the given size holding data and checks
+// that bits [size, size+256) are a copy of bits [0, 256), and that no other
+// bit before the slice and no byte after it is changed.
+func checkSliceRotor(t *testing.T, size int16, data []byte) {
+	t.Helper()
+	r := &Rotor{Size: size, Rotor: append([]byte(nil), data...)}
+	sliceRotor(r)
+	s := int(size)
+	for i := range s {
+		if rotorBit(r.Rotor, i) != rotorBit(data, i) {
+			t.Fatalf("size %d: bit %d of the rotor was changed", size, i)
+		}
+	}
+	for i := range 256 {
+		if rotorBit(r.Rotor, s+i) != rotorBit(data, i) {
+			t.Fatalf("size %d: bit %d of the slice is not bit %d of the rotor", size, s+i, i)
+		}
+	}
+	for i := (s+255)/8 + 1; i < len(data); i++ {
+		if r.Rotor[i] != data[i] {
+			t.Fatalf("size %d: byte %d after the slice was changed", size, i)
+		}
+	}
+}
+
+// TestSliceRotor slices rotors of every size from 256 to the largest, covering
+// both the byte aligned and the unaligned copies, with random contents.
+func TestSliceRotor(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	data := make([]byte, 256)
+	for size := int16(256); size <= maxRotorSize; size++ {
+		for range 4 {
+			for i := range data {
+				data[i] = byte(rnd.Uint32())
+			}
+			checkSliceRotor(t, size, data)
+		}
+	}
+	// All ones and all zeros catch bits that are set or cleared instead of
+	// copied.
+	for _, fill := range []byte{0x00, 0xff} {
+		for i := range data {
+			data[i] = fill
+		}
+		for size := int16(256); size <= maxRotorSize; size++ {
+			checkSliceRotor(t, size, data)
+		}
+	}
+}
+
+func FuzzSliceRotor(f *testing.F) {
+	f.Add(uint16(0), []byte{0x01})
+	f.Add(uint16(1789-256), []byte{0xff, 0x00, 0xa5, 0x5a})
+	f.Add(uint16(maxRotorSize-256), []byte{0x80})
+	f.Fuzz(func(t *testing.T, n uint16, contents []byte) {
+		size := int16(256 + int(n)%(maxRotorSize-256+1))
+		data := make([]byte, 256)
+		// Repeat the contents so that the whole rotor is covered.
+		for i := 0; len(contents) != 0 && i < len(data); i += len(contents) {
+			copy(data[i:], contents)
+		}
+		checkSliceRotor(t, size, data)
+	})
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// rustSource formats the rotors and permutators as Rust source code.  The
+// structs use the same field names and sizes as the Rotor, Cycle and
+// Permutator types used in IkMachine.  It is an error for the rotors or the
+// permutators to differ in size, as the module declares them as arrays.
+func rustSource(m *Machine) (string, error) {
+	if err := m.checkFixedSizes(); err != nil {
+		return "", err
+	}
+	var output bytes.Buffer
+	rotors, permutators := m.Rotors, m.Permutators
+	output.WriteString("// Code generated by genProforma. DO NOT EDIT.\n")
+	output.WriteString(m.fingerprintComment("// ") + "\n")
+	output.WriteString(fmt.Sprintf("pub const PROFORMA_ROTOR_COUNT: usize = %d;\n", len(rotors)))
+	output.WriteString(fmt.Sprintf("pub const PROFORMA_ROTOR_BYTES: usize = %d;\n", len(rotors[0].Rotor)))
+	output.WriteString(fmt.Sprintf("pub const PROFORMA_PERMUTATOR_COUNT: usize = %d;\n", len(permutators)))
+	output.WriteString(fmt.Sprintf("pub const PROFORMA_CYCLE_COUNT: usize = %d;\n\n", len(permutators[0].Cycles)))
+	output.WriteString("#[derive(Clone, Copy, Debug)]\n" +
+		"pub struct Rotor {\n" +
+		"    pub size: i16,    // the size in bits for this rotor\n" +
+		"    pub start: i16,   // the initial starting position of the rotor\n" +
+		"    pub step: i16,    // the step size in bits for this rotor\n" +
+		"    pub current: i16, // the current position of this rotor\n" +
+		"    pub rotor: [u8; PROFORMA_ROTOR_BYTES],\n" +
+		"}\n\n")
+	output.WriteString("#[derive(Clone, Copy, Debug)]\n" +
+		"pub struct Cycle {\n" +
+		"    pub start: i16,   // The starting point (into randp) for this cycle.\n" +
+		"    pub length: i16,  // The length of the cycle.\n" +
+		"    pub current: i16, // The point in the cycle [0 .. cycles.length-1] to start\n" +
+		"}\n\n")
+	output.WriteString("#[allow(non_snake_case)]\n" +
+		"#[derive(Clone, Copy, Debug)]\n" +
+		"pub struct Permutator {\n" +
+		"    pub currentState: i32,  // Current number of cycles for this permutator.\n" +
+		"    pub maximalStates: i32, // Maximum number of cycles before repeating.\n" +
+		"    pub cycles: [Cycle; PROFORMA_CYCLE_COUNT],\n" +
+		"    pub randp: [u8; 256],   // Values 0 - 255 in a random order.\n" +
+		"    pub bitPerm: [u8; 256], // Permutation table created from randp.\n" +
+		"}\n\n")
+	output.WriteString("#[rustfmt::skip]\n" +
+		"pub static PROFORMA_ROTORS: [Rotor; PROFORMA_ROTOR_COUNT] = [\n")
+	for _, r := range rotors {
+		output.WriteString("    Rotor {\n")
+		output.WriteString(fmt.Sprintf("        size:    %d,\n", r.Size))
+		output.WriteString(fmt.Sprintf("        start:   %d,\n", r.Start))
+		output.WriteString(fmt.Sprintf("        step:    %d,\n", r.Step))
+		output.WriteString(fmt.Sprintf("        current: %d,\n", r.Current))
+		output.WriteString("        rotor:   [\n")
+		writeHexBytes(&output, "            ", r.Rotor)
+		output.WriteString("        ],\n    },\n")
+	}
+	output.WriteString("];\n\n")
+	output.WriteString("#[rustfmt::skip]\n" +
+		"pub static PROFORMA_PERMUTATOR: [Permutator; PROFORMA_PERMUTATOR_COUNT] = [\n")
+	for _, p := range permutators {
+		output.WriteString("    Permutator {\n")
+		output.WriteString(fmt.Sprintf("        currentState:  %d,\n", p.CurrentState))
+		output.WriteString(fmt.Sprintf("        maximalStates: %d,\n", p.MaximalStates))
+		output.WriteString("        cycles: [\n")
+		for _, c := range p.Cycles {
+			output.WriteString(fmt.Sprintf("            Cycle { start: %d, length: %d, current: %d },\n",
+				c.Start, c.Length, c.Current))
+		}
+		output.WriteString("        ],\n        randp: [\n")
+		writeHexBytes(&output, "            ", p.Randp)
+		output.WriteString("        ],\n        bitPerm: [\n")
+		writeHexBytes(&output, "            ", p.bitPerm[:])
+		output.WriteString("        ],\n    },\n")
+	}
+	output.WriteString("];\n")
+	return output.String(), nil
+}
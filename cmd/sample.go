@@ -0,0 +1,130 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "math/bits"
+
+// sampler draws uniformly distributed values from a stream of random bytes.
+// Every source of random data (crypto/rand, the keyed engines, files, dice
+// and mixed sources) only supplies the bytes; the rotor starts and steps, the
+// cycle order and the randp permutations are all sampled by a sampler, so
+// every source is sampled the same way.
+//
+// Int63n uses rejection sampling: a candidate is made from just enough bytes
+// to hold n-1, masked to the bit length of n-1, and rejected if it is not
+// less than n.  Every accepted candidate is equally likely, so there is no
+// modulo bias.  Perm is a Fisher-Yates shuffle using Int63n, so every
+// permutation is equally likely.
+type sampler struct {
+	read       func([]byte) (int, error)
+	concurrent bool
+}
+
+// Concurrent reports whether read is safe for concurrent use.
+func (s sampler) Concurrent() bool {
+	return s.concurrent
+}
+
+// Read fills p with random bytes from the stream.
+func (s sampler) Read(p []byte) (int, error) {
+	return s.read(p)
+}
+
+// Int63n returns a uniformly distributed value in [0, n).  It panics if
+// n <= 0.
+func (s sampler) Int63n(n int64) (int64, error) {
+	if n <= 0 {
+		panic("argument to Int63n is <= 0")
+	}
+	bitLen := bits.Len64(uint64(n - 1))
+	if bitLen == 0 {
+		return 0, nil
+	}
+	buf := make([]byte, (bitLen+7)/8)
+	mask := byte(0xff >> (8*len(buf) - bitLen))
+	for {
+		if _, err := s.read(buf); err != nil {
+			return 0, err
+		}
+		buf[0] &= mask
+		var v int64
+		for _, b := range buf {
+			v = v<<8 | int64(b)
+		}
+		if v < n {
+			return v, nil
+		}
+	}
+}
+
+// Perm returns a uniformly distributed permutation of the values [0, n).  It
+// panics if n < 0.
+func (s sampler) Perm(n int) ([]int, error) {
+	if n < 0 {
+		panic("argument to Perm is < 0")
+	}
+	res := make([]int, n)
+	// The "inside-out" Fisher-Yates shuffle.
+	for i := 1; i < n; i++ {
+		j, err := s.Int63n(int64(i + 1))
+		if err != nil {
+			return nil, err
+		}
+		res[i] = res[j]
+		res[j] = i
+	}
+	return res, nil
+}
+
+// engineSampler samples the way releases before the sampler did, so that the
+// machines made from a passphrase by those releases can be made again.  The
+// values are drawn with the keyed engine's own Int63n, and the randp, the only
+// permutation of 256 values, with the engine's own Perm.  That Perm is
+// Sattolo's algorithm, so every randp is a single cycle through all 256
+// values instead of a uniformly distributed permutation.
+type engineSampler struct {
+	read   func([]byte) (int, error)
+	int63n func(int64) int64
+	perm   func(int) []int
+}
+
+// Read fills p with random bytes from the engine.
+func (s engineSampler) Read(p []byte) (int, error) {
+	return s.read(p)
+}
+
+// Int63n returns the engine's value in [0, n).  It panics if n <= 0.
+func (s engineSampler) Int63n(n int64) (int64, error) {
+	return s.int63n(n), nil
+}
+
+// Perm returns the engine's permutation of the values [0, n) for a randp, and
+// an "inside-out" shuffle using the engine's Int63n for the cycle order.
+func (s engineSampler) Perm(n int) ([]int, error) {
+	if n == 256 {
+		return s.perm(n), nil
+	}
+	if n < 0 {
+		panic("argument to Perm is < 0")
+	}
+	res := make([]int, n)
+	for i := 1; i < n; i++ {
+		j := s.int63n(int64(i + 1))
+		res[i] = res[j]
+		res[j] = i
+	}
+	return res, nil
+}
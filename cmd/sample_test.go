@@ -0,0 +1,253 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// candidateReader returns the bytes of first on its first read and zeros on
+// every read after that.
+type candidateReader struct {
+	first []byte
+	reads int
+}
+
+func (c *candidateReader) Read(p []byte) (int, error) {
+	c.reads++
+	if c.reads == 1 {
+		return copy(p, c.first), nil
+	}
+	clear(p)
+	return len(p), nil
+}
+
+// TestInt63nRejection checks every possible first candidate for a range of n:
+// each value in [0, n) must be produced by the same number of candidates, and
+// every other candidate must be rejected.
+func TestInt63nRejection(t *testing.T) {
+	ns := []int64{1, 2, 3, 5, 7, 61, 67, 128, 129, 255, 256, 257, 300, 1747, 1789}
+	for _, n := range ns {
+		width := 1
+		if n > 256 {
+			width = 2
+		}
+		hits := make(map[int64]int)
+		rejected := 0
+		for c := 0; c < 1<<(8*width); c++ {
+			first := make([]byte, width)
+			for i := range first {
+				first[i] = byte(c >> (8 * (width - 1 - i)))
+			}
+			r := &candidateReader{first: first}
+			v, err := sampler{read: r.Read}.Int63n(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v < 0 || v >= n {
+				t.Fatalf("Int63n(%d) = %d", n, v)
+			}
+			if n > 1 && r.reads == 1 {
+				hits[v]++
+			} else if n > 1 {
+				rejected++
+			}
+		}
+		if n == 1 {
+			continue
+		}
+		if int64(len(hits)) != n {
+			t.Errorf("Int63n(%d): %d of %d values reachable", n, len(hits), n)
+		}
+		want := hits[0]
+		for v, cnt := range hits {
+			if cnt != want {
+				t.Errorf("Int63n(%d): value %d produced by %d candidates, want %d", n, v, cnt, want)
+			}
+		}
+		// The mask keeps more than half of the candidates.
+		if rejected*2 >= 1<<(8*width) {
+			t.Errorf("Int63n(%d): %d candidates rejected", n, rejected)
+		}
+	}
+}
+
+func TestSamplerErrors(t *testing.T) {
+	errRead := errors.New("read failed")
+	s := sampler{read: func([]byte) (int, error) { return 0, errRead }}
+	if _, err := s.Int63n(10); !errors.Is(err, errRead) {
+		t.Errorf("Int63n error = %v, want %v", err, errRead)
+	}
+	if _, err := s.Perm(10); !errors.Is(err, errRead) {
+		t.Errorf("Perm error = %v, want %v", err, errRead)
+	}
+	// Int63n(1) and Perm(1) need no random data.
+	if v, err := s.Int63n(1); v != 0 || err != nil {
+		t.Errorf("Int63n(1) = %d, %v", v, err)
+	}
+	if p, err := s.Perm(1); len(p) != 1 || err != nil {
+		t.Errorf("Perm(1) = %v, %v", p, err)
+	}
+}
+
+// TestPermUniform checks that every permutation of 4 values is equally
+// likely.
+func TestPermUniform(t *testing.T) {
+	s := sampler{read: newHmacStream([]byte("perm")).Read}
+	const trials = 24 * 1000
+	counts := make(map[[4]int]int)
+	for range trials {
+		p, err := s.Perm(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[[4]int(p)]++
+	}
+	if len(counts) != 24 {
+		t.Fatalf("%d of 24 permutations seen", len(counts))
+	}
+	expected := make([]float64, 24)
+	observed := make([]int, 0, 24)
+	for _, cnt := range counts {
+		expected[len(observed)] = trials / 24
+		observed = append(observed, cnt)
+	}
+	// The critical value for 23 degrees of freedom at p = 0.001.
+	if x := chiSquare(observed, expected); x > 49.73 {
+		t.Errorf("chi-square = %.2f, the permutations are not uniform", x)
+	}
+}
+
+// TestMachineUniform generates many machines and checks that the rotor starts
+// and steps, and the randp values, are uniformly distributed.
+func TestMachineUniform(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the statistical test in short mode")
+	}
+	saved := source
+	t.Cleanup(func() { source = saved })
+	source = NewStreamSource(newHmacStream([]byte("uniform")).Read)
+	const machines = 2000
+	const bins = 16
+	starts := make([]int, bins)
+	steps := make([]int, bins)
+	startExp := make([]float64, bins)
+	stepExp := make([]float64, bins)
+	randp := make([]int, bins)
+	randpExp := make([]float64, bins)
+	fixed, permutations := 0, 0
+	for range machines {
+		m, err := newMachine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range m.Rotors {
+			size := int(r.Size)
+			starts[int(r.Start)*bins/size]++
+			steps[int(r.Step-1)*bins/(size-1)]++
+			// The expected count of each bin is the number of values in it.
+			for v := range size {
+				startExp[v*bins/size] += 1.0 / float64(size)
+			}
+			for v := range size - 1 {
+				stepExp[v*bins/(size-1)] += 1.0 / float64(size-1)
+			}
+		}
+		permutations += len(m.Permutators)
+		for _, p := range m.Permutators {
+			randp[int(p.Randp[0])*bins/256]++
+			randp[int(p.Randp[255])*bins/256]++
+			for i := range randpExp {
+				randpExp[i] += 2.0 / bins
+			}
+			for i, v := range p.Randp {
+				if int(v) == i {
+					fixed++
+				}
+			}
+		}
+	}
+	// The critical value for 15 degrees of freedom at p = 0.001.
+	const critical = 37.70
+	for _, tc := range []struct {
+		name     string
+		observed []int
+		expected []float64
+	}{
+		{"Start", starts, startExp},
+		{"Step", steps, stepExp},
+		{"Randp", randp, randpExp},
+	} {
+		if x := chiSquare(tc.observed, tc.expected); x > critical {
+			t.Errorf("%s: chi-square = %.2f, the values are not uniform", tc.name, x)
+		}
+	}
+	// A uniform permutation has one fixed point on average, with a variance
+	// of one.
+	if avg := float64(fixed) / float64(permutations); avg < 0.9 || avg > 1.1 {
+		t.Errorf("average of %.3f fixed points in randp, want 1", avg)
+	}
+}
+
+func chiSquare(observed []int, expected []float64) float64 {
+	x := 0.0
+	for i, o := range observed {
+		d := float64(o) - expected[i]
+		x += d * d / expected[i]
+	}
+	return x
+}
+
+// TestLegacySampling checks that --legacy-sampling makes the machine that a
+// release before the rejection sampler made from the same passphrase, kept
+// in testdata/legacy-tntengine.json, and that its randp are single cycles.
+func TestLegacySampling(t *testing.T) {
+	want, err := loadProFormaFile(filepath.Join("testdata", "legacy-tntengine.json"), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := legacySampling
+	t.Cleanup(func() { legacySampling = saved })
+	for _, legacy := range []bool{true, false} {
+		legacySampling = legacy
+		s, err := newSource("tntengine", []string{"matrix", "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeSource(s)
+		m, err := generateMachine(s, engineLayout, rotorSizes, cycleSizes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same := m.fingerprint() == want.fingerprint(); same != legacy {
+			t.Errorf("legacy sampling %v: the machine is the legacy machine = %v", legacy, same)
+		}
+		if !legacy {
+			continue
+		}
+		for i, p := range m.Permutators {
+			n, v := 1, p.Randp[0]
+			for ; v != 0; v = p.Randp[v] {
+				n++
+			}
+			if n != 256 {
+				t.Errorf("the randp of permutator %d has a cycle of %d values, want 256", i+1, n)
+			}
+		}
+	}
+}
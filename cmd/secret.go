@@ -0,0 +1,107 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+var (
+	// label, if set, is used to derive the key given to the engine from the
+	// (master) secret, so that one secret can create many machines.
+	label string
+	// legacySampling makes the tntengine source sample with their own Int63n
+	// and Perm, as releases before the rejection sampler did.
+	legacySampling bool
+)
+
+// labelSalt is the HKDF salt used to derive the labeled keys.  Changing it
+// changes every labeled machine.
+const labelSalt = "genProforma label v1"
+
+// getSecret obtains the secret used to key the engine.  If a label was given,
+// the secret is treated as a master secret and the key for the label is
+// derived from it.
+func getSecret(args []string) (string, error) {
+	// Obtain the passphrase used to encrypt the file from either:
+	// 1. User input from the terminal (most secure)
+	// 2. The 'GPF_SECRET' environment variable (less secure)
+	// 3. Arguments from the entered command line (least secure - not recommended)
+	var secret string
+	if len(args) == 0 {
+		if viper.IsSet("GPF_SECRET") {
+			secret = viper.GetString("GPF_SECRET")
+		} else {
+			if term.IsTerminal(int(os.Stdin.Fd())) {
+				fmt.Fprintf(os.Stderr, "Enter the passphrase: ")
+				byteSecret, err := term.ReadPassword(int(os.Stdin.Fd()))
+				if err != nil {
+					return "", withExitCode(exitSecret, fmt.Errorf("reading the passphrase: %w", err))
+				}
+				fmt.Fprintln(os.Stderr, "")
+				secret = string(byteSecret)
+			}
+		}
+	} else {
+		secret = strings.Join(args, " ")
+	}
+
+	if len(secret) == 0 {
+		return "", withExitCode(exitSecret, errors.New("you must supply a password"))
+	}
+	if len(label) != 0 {
+		secret = labelKey(secret, label)
+	}
+	return secret, nil
+}
+
+// labelKey derives the key for label from the master secret using HKDF-SHA256
+// (RFC 5869) with the label as the info parameter.  The 32 byte key is
+// returned hex encoded, so it can also be given directly as a passphrase to
+// reproduce the labeled machine without the master secret.
+func labelKey(secret, label string) string {
+	return hex.EncodeToString(hkdf([]byte(secret), []byte(labelSalt), []byte(label), sha256.Size))
+}
+
+// hkdf implements the HKDF extract and expand steps from RFC 5869 using
+// HMAC-SHA256, returning length bytes of keying material.
+func hkdf(secret, salt, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	res := make([]byte, 0, length)
+	var t []byte
+	expand := hmac.New(sha256.New, prk)
+	for i := byte(1); len(res) < length; i++ {
+		expand.Reset()
+		expand.Write(t)
+		expand.Write(info)
+		expand.Write([]byte{i})
+		t = expand.Sum(nil)
+		res = append(res, t...)
+	}
+	return res[:length]
+}
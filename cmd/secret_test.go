@@ -0,0 +1,89 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// byteRange returns the byte values [from, to).
+func byteRange(from, to int) []byte {
+	res := make([]byte, 0, to-from)
+	for i := from; i < to; i++ {
+		res = append(res, byte(i))
+	}
+	return res
+}
+
+// TestHKDF checks hkdf against the SHA-256 test cases of RFC 5869, appendix A.
+func TestHKDF(t *testing.T) {
+	tests := []struct {
+		name         string
+		secret, salt []byte
+		info         []byte
+		length       int
+		okm          string
+	}{
+		{"A.1", bytes.Repeat([]byte{0x0b}, 22), byteRange(0x00, 0x0d), byteRange(0xf0, 0xfa), 42,
+			"3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"},
+		{"A.2", byteRange(0x00, 0x50), byteRange(0x60, 0xb0), byteRange(0xb0, 0x100), 82,
+			"b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c" +
+				"59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71" +
+				"cc30c58179ec3e87c14c01d5c1f3434f1d87"},
+		{"A.3", bytes.Repeat([]byte{0x0b}, 22), nil, nil, 42,
+			"8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"},
+	}
+	for _, tc := range tests {
+		if got := hex.EncodeToString(hkdf(tc.secret, tc.salt, tc.info, tc.length)); got != tc.okm {
+			t.Errorf("%s: okm = %s, want %s", tc.name, got, tc.okm)
+		}
+	}
+}
+
+// TestLabelMachines checks that a label reproduces its own machine, and that
+// different labels, or no label, make different machines from one secret.
+func TestLabelMachines(t *testing.T) {
+	saved := label
+	t.Cleanup(func() { label = saved })
+	machine := func(l string) [sha256.Size]byte {
+		t.Helper()
+		label = l
+		s, err := newSource("tntengine", []string{"master", "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeSource(s)
+		m, err := generateMachine(s, engineLayout, rotorSizes, cycleSizes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m.fingerprint()
+	}
+	if machine("alice") != machine("alice") {
+		t.Errorf("the same label made two machines")
+	}
+	fingerprints := map[[sha256.Size]byte]string{}
+	for _, l := range []string{"", "alice", "bob", "Alice"} {
+		fp := machine(l)
+		if other, dup := fingerprints[fp]; dup {
+			t.Errorf("the labels %q and %q made the same machine", other, l)
+		}
+		fingerprints[fp] = l
+	}
+}
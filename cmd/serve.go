@@ -0,0 +1,549 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	listenAddr    string
+	tlsCertFile   string
+	tlsKeyFile    string
+	clientCAFile  string
+	rateLimit     float64
+	rateBurst     int
+	serveBackends []string
+)
+
+// maxRequestBytes is the largest request body accepted by the server.
+const maxRequestBytes = 1 << 20
+
+// defaultBackend is the backend used by the servers when a request does not
+// name one, as documented in proformapb/proforma.proto.
+const defaultBackend = "random"
+
+// keyedSources are the sources that need a secret to create a machine.
+var keyedSources = []string{"tntengine", "ikmachine"}
+
+// serverSources are the sources that can be used by the servers.  The other
+// sources read from the operator or from local files, or hand out the shares
+// of a master seed, so a new source must be added here to be served.
+var serverSources = []string{"random", "tntengine", "ikmachine", "mixed"}
+
+// contentTypes gives the content type returned for each output type.
+var contentTypes = map[string]string{
+	"json":   "application/json",
+	"ikm":    "text/x-go; charset=utf-8",
+	"c":      "text/x-c; charset=utf-8",
+	"rust":   "text/x-rust; charset=utf-8",
+	"python": "text/x-python; charset=utf-8",
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve an HTTP API that generates and verifies proforma machines",
+	Long: `Serve an HTTP API that generates and verifies proforma machines.
+
+  POST /v1/generate  Generate a machine.  The request body is a JSON object:
+                     {"layout": "rrprrprr", "backend": "random",
+                      "outputType": "json", "secret": "", "label": ""}
+                     All of the fields are optional, the values shown are
+                     the defaults.  The secret (and label) are required by
+                     the keyed backends.  The machine is returned in the
+                     requested output type, with its fingerprint in the
+                     X-Proforma-Fingerprint header.
+  POST /v1/verify    Verify the machine in the request body.  The input type
+                     is given by the "type" query parameter (default json).
+  GET  /healthz      Health check.
+  GET  /metrics      Metrics in the Prometheus text format.
+
+Only the backends given by --backend may be used, and only the random,
+tntengine, ikmachine and mixed backends can be served.  With --tls-cert and
+--tls-key the server uses TLS, and with --client-ca it also requires client
+certificates signed by that CA (mTLS).  Each client, identified by the common
+name of its certificate or else by its address, is limited to --rate requests
+per second (the health check and metrics are not limited).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&listenAddr, "listen", "localhost:8080", "address to listen on")
+	addServerFlags(serveCmd)
+}
+
+// addServerFlags adds the flags shared by the servers to cmd.
+func addServerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&tlsCertFile, "tls-cert", "", "TLS certificate file")
+	cmd.Flags().StringVar(&tlsKeyFile, "tls-key", "", "TLS private key file")
+	cmd.Flags().StringVar(&clientCAFile, "client-ca", "", "require client certificates signed by the CAs in this file")
+	cmd.Flags().Float64Var(&rateLimit, "rate", 1, "requests per second allowed for each client (0 for no limit)")
+	cmd.Flags().IntVar(&rateBurst, "burst", 10, "number of requests a client may make at once")
+	cmd.Flags().StringSliceVar(&serveBackends, "backend", []string{"random"}, "backends that may be used to generate machines")
+}
+
+func serve() error {
+	if err := checkServeBackends(); err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              listenAddr,
+		Handler:           newProformaServer(serveBackends, rateLimit, rateBurst),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("Serving proforma machines on %s", listenAddr)
+		if srv.TLSConfig != nil {
+			errc <- srv.ListenAndServeTLS("", "")
+		} else {
+			errc <- srv.ListenAndServe()
+		}
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
+
+// checkServeBackends makes sure that the backends given by --backend can be
+// used by a server.
+func checkServeBackends() error {
+	if len(serveBackends) == 0 {
+		return errors.New("no backends were given")
+	}
+	for _, name := range serveBackends {
+		if !slices.Contains(Sources(), name) {
+			return fmt.Errorf("%s is not a registered source", name)
+		}
+		if !slices.Contains(serverSources, name) {
+			return fmt.Errorf("the %s source cannot be used by the server", name)
+		}
+	}
+	return nil
+}
+
+// serverTLSConfig returns the TLS configuration given by the --tls-cert,
+// --tls-key and --client-ca flags, or nil if TLS is not used.
+func serverTLSConfig() (*tls.Config, error) {
+	if len(tlsCertFile) == 0 && len(tlsKeyFile) == 0 {
+		if len(clientCAFile) != 0 {
+			return nil, errors.New("--client-ca needs --tls-cert and --tls-key")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if len(clientCAFile) != 0 {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// generateRequest is the body of a request to generate a machine.
+type generateRequest struct {
+	Layout     string `json:"layout"`
+	Backend    string `json:"backend"`
+	OutputType string `json:"outputType"`
+	Secret     string `json:"secret"`
+	Label      string `json:"label"`
+}
+
+// verifyResponse is the result of verifying a machine.
+type verifyResponse struct {
+	Valid       bool     `json:"valid"`
+	Layout      string   `json:"layout,omitempty"`
+	Fingerprint string   `json:"fingerprint,omitempty"`
+	SHA256      string   `json:"sha256,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// proformaServer serves the HTTP API.
+type proformaServer struct {
+	mux      *http.ServeMux
+	backends []string
+	limiter  *rateLimiter
+	metrics  *serveMetrics
+}
+
+// newProformaServer returns the handler for the HTTP API, allowing the given
+// backends and limiting each client to rate requests per second.
+func newProformaServer(backends []string, rate float64, burst int) *proformaServer {
+	s := &proformaServer{
+		mux:      http.NewServeMux(),
+		backends: backends,
+		metrics:  newServeMetrics(),
+	}
+	if rate > 0 {
+		s.limiter = newRateLimiter(rate, burst)
+	}
+	s.mux.HandleFunc("POST /v1/generate", s.generate)
+	s.mux.HandleFunc("POST /v1/verify", s.verify)
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	s.mux.HandleFunc("GET /metrics", s.metrics.write)
+	return s
+}
+
+func (s *proformaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	_, pattern := s.mux.Handler(r)
+	if len(pattern) == 0 {
+		pattern = "unmatched"
+	}
+	defer func() { s.metrics.request(pattern, rec.status) }()
+	if s.limiter != nil && r.URL.Path != "/healthz" && r.URL.Path != "/metrics" {
+		if wait, ok := s.limiter.allow(clientID(r)); !ok {
+			s.metrics.rateLimited()
+			rec.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			httpError(rec, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+			return
+		}
+	}
+	r.Body = http.MaxBytesReader(rec, r.Body, maxRequestBytes)
+	s.mux.ServeHTTP(rec, r)
+}
+
+func (s *proformaServer) generate(w http.ResponseWriter, r *http.Request) {
+	var req generateRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Layout) == 0 {
+		req.Layout = engineLayout
+	}
+	if len(req.Backend) == 0 {
+		req.Backend = defaultBackend
+	}
+	if len(req.OutputType) == 0 {
+		req.OutputType = "json"
+	}
+	if !slices.Contains(s.backends, req.Backend) {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("the %s backend is not available", req.Backend))
+		return
+	}
+	if !slices.Contains(outputTypes, req.OutputType) {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("%s is not a valid output type", req.OutputType))
+		return
+	}
+	m, err := generateFrom(req.Backend, req.Layout, req.Secret, req.Label)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	var body bytes.Buffer
+	if err := writeProForma(&body, req.OutputType, m); err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.metrics.generated(req.Backend)
+	sum := m.fingerprint()
+	w.Header().Set("Content-Type", contentTypes[req.OutputType])
+	w.Header().Set("X-Proforma-Source", req.Backend)
+	w.Header().Set("X-Proforma-Fingerprint", m.fingerprintText())
+	w.Header().Set("X-Proforma-Sha256", hex.EncodeToString(sum[:]))
+	w.Write(body.Bytes())
+}
+
+func (s *proformaServer) verify(w http.ResponseWriter, r *http.Request) {
+	iType := r.URL.Query().Get("type")
+	if len(iType) == 0 {
+		iType = "json"
+	}
+	if !slices.Contains(inputTypes, iType) {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("%s is not a valid input type", iType))
+		return
+	}
+	res, status := verifyReader(r.Body, iType)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+// generateFrom generates a machine with the given layout from the named
+// backend.  The keyed backends need a secret, optionally with a label to
+// derive the key from.
+func generateFrom(backend, layout, secret, label string) (*Machine, error) {
+	if err := checkLayout(layout, rotorSizes); err != nil {
+		return nil, err
+	}
+	var args []string
+	if slices.Contains(keyedSources, backend) {
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("the %s backend needs a secret", backend)
+		}
+		if len(label) != 0 {
+			secret = labelKey(secret, label)
+		}
+		args = []string{secret}
+	} else if len(secret) != 0 || len(label) != 0 {
+		return nil, fmt.Errorf("the %s backend does not use a secret", backend)
+	}
+	s, err := newSource(backend, args)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSource(s)
+	return generateMachine(s, layout, rotorSizes, cycleSizes)
+}
+
+// verifyReader loads the machine from r and verifies it, returning the result
+// and the HTTP status for it.
+func verifyReader(r io.Reader, iType string) (verifyResponse, int) {
+	m, err := loadProForma(r, iType)
+	if err == nil {
+		err = m.verify()
+	}
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		status := http.StatusUnprocessableEntity
+		if errors.As(err, &maxErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		return verifyResponse{Errors: errorList(err)}, status
+	}
+	sum := m.fingerprint()
+	return verifyResponse{
+		Valid:       true,
+		Layout:      m.Layout,
+		Fingerprint: m.fingerprintText(),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}, http.StatusOK
+}
+
+// errorList returns the messages of the errors joined in err.
+func errorList(err error) []string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var res []string
+		for _, e := range joined.Unwrap() {
+			res = append(res, errorList(e)...)
+		}
+		return res
+	}
+	return []string{err.Error()}
+}
+
+// httpError writes err to w as a JSON error response.
+func httpError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// clientID identifies the client making the request: the common name of its
+// verified certificate, or else its address.
+func clientID(r *http.Request) string {
+	return peerID(r.TLS, r.RemoteAddr)
+}
+
+// peerID identifies a client by the common name of its verified certificate,
+// or else by its address.
+func peerID(state *tls.ConnectionState, addr string) string {
+	if state != nil && len(state.VerifiedChains) != 0 {
+		return "cn:" + state.VerifiedChains[0][0].Subject.CommonName
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// statusRecorder records the status code written to a ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// rateLimiter is a token bucket for each client.  A bucket holds up to burst
+// tokens and is refilled at rate tokens per second; each request takes one.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from the client's bucket.  If there are none it returns
+// false and how long until there will be one.
+func (l *rateLimiter) allow(client string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	// Forget the clients whose buckets have refilled, so the map does not
+	// grow without limit.
+	if full := time.Duration(l.burst / l.rate * float64(time.Second)); now.Sub(l.swept) > full {
+		for id, b := range l.buckets {
+			if now.Sub(b.last) > full {
+				delete(l.buckets, id)
+			}
+		}
+		l.swept = now
+	}
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// serveMetrics counts the requests handled by the server.
+type serveMetrics struct {
+	mu             sync.Mutex
+	requests       map[[2]string]uint64 // by pattern and status code
+	machines       map[string]uint64    // by backend
+	rateLimitedCnt uint64
+}
+
+func newServeMetrics() *serveMetrics {
+	return &serveMetrics{
+		requests: make(map[[2]string]uint64),
+		machines: make(map[string]uint64),
+	}
+}
+
+func (m *serveMetrics) request(pattern string, status int) {
+	m.mu.Lock()
+	m.requests[[2]string{pattern, strconv.Itoa(status)}]++
+	m.mu.Unlock()
+}
+
+func (m *serveMetrics) generated(backend string) {
+	m.mu.Lock()
+	m.machines[backend]++
+	m.mu.Unlock()
+}
+
+func (m *serveMetrics) rateLimited() {
+	m.mu.Lock()
+	m.rateLimitedCnt++
+	m.mu.Unlock()
+}
+
+// write writes the metrics in the Prometheus text format.
+func (m *serveMetrics) write(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintln(w, "# HELP genproforma_http_requests_total HTTP requests handled, by pattern and status code.")
+	fmt.Fprintln(w, "# TYPE genproforma_http_requests_total counter")
+	keys := make([][2]string, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "genproforma_http_requests_total{pattern=%q,code=%q} %d\n", k[0], k[1], m.requests[k])
+	}
+	fmt.Fprintln(w, "# HELP genproforma_machines_generated_total Proforma machines generated, by backend.")
+	fmt.Fprintln(w, "# TYPE genproforma_machines_generated_total counter")
+	backends := make([]string, 0, len(m.machines))
+	for k := range m.machines {
+		backends = append(backends, k)
+	}
+	sort.Strings(backends)
+	for _, k := range backends {
+		fmt.Fprintf(w, "genproforma_machines_generated_total{backend=%q} %d\n", k, m.machines[k])
+	}
+	fmt.Fprintln(w, "# HELP genproforma_rate_limited_total Requests rejected by the rate limit.")
+	fmt.Fprintln(w, "# TYPE genproforma_rate_limited_total counter")
+	fmt.Fprintf(w, "genproforma_rate_limited_total %d\n", m.rateLimitedCnt)
+}
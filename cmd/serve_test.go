@@ -0,0 +1,453 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// post sends body to the path on the test server, returning the response and
+// its body.
+func post(t *testing.T, srv *httptest.Server, path, body string) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := srv.Client().Post(srv.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, res
+}
+
+// checkGoroutines makes the request 20 times and checks that the number of
+// goroutines does not grow with the number of requests.
+func checkGoroutines(t *testing.T, request func()) {
+	t.Helper()
+	// The first request starts the goroutines of the connection.
+	request()
+	before := runtime.NumGoroutine()
+	for range 20 {
+		request()
+	}
+	// Goroutines that are finishing may take a moment to exit.
+	for deadline := time.Now().Add(2 * time.Second); runtime.NumGoroutine() > before+2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before+2 {
+		t.Errorf("20 requests took the number of goroutines from %d to %d", before, n)
+	}
+}
+
+// TestServeGoroutines checks that the tntengine backend is shut down after
+// each request.
+func TestServeGoroutines(t *testing.T) {
+	srv := httptest.NewServer(newProformaServer([]string{"tntengine"}, 0, 0))
+	defer srv.Close()
+	checkGoroutines(t, func() {
+		resp, body := post(t, srv, "/v1/generate", `{"backend": "tntengine", "secret": "secret"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d: %s", resp.StatusCode, body)
+		}
+	})
+}
+
+// TestServeGenerate checks the status codes and headers of /v1/generate.
+func TestServeGenerate(t *testing.T) {
+	srv := httptest.NewServer(newProformaServer([]string{"random", "tntengine"}, 0, 0))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"defaults", ``, http.StatusOK},
+		{"empty", `{}`, http.StatusOK},
+		{"keyed", `{"backend": "tntengine", "secret": "secret", "layout": "rprp"}`, http.StatusOK},
+		{"every output type", `{"outputType": "python"}`, http.StatusOK},
+		{"no secret", `{"backend": "tntengine"}`, http.StatusBadRequest},
+		{"unused secret", `{"backend": "random", "secret": "secret"}`, http.StatusBadRequest},
+		{"backend not allowed", `{"backend": "ikmachine", "secret": "secret"}`, http.StatusBadRequest},
+		{"output type", `{"outputType": "xml"}`, http.StatusBadRequest},
+		{"layout", `{"layout": "rrx"}`, http.StatusBadRequest},
+		{"unknown field", `{"colour": "blue"}`, http.StatusBadRequest},
+		{"not json", `[`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := post(t, srv, "/v1/generate", tc.body)
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tc.status, body)
+			}
+			if tc.status != http.StatusOK {
+				var res map[string]string
+				if err := json.Unmarshal(body, &res); err != nil || len(res["error"]) == 0 {
+					t.Errorf("the error response %q has no error", body)
+				}
+				return
+			}
+			if len(resp.Header.Get("X-Proforma-Fingerprint")) == 0 || len(resp.Header.Get("X-Proforma-Sha256")) == 0 {
+				t.Errorf("the response has no fingerprint headers")
+			}
+		})
+	}
+
+	// A keyed backend gives the same machine for the same secret.
+	keyed := `{"backend": "tntengine", "secret": "secret", "label": "a"}`
+	first, body := post(t, srv, "/v1/generate", keyed)
+	again, _ := post(t, srv, "/v1/generate", keyed)
+	if fp := first.Header.Get("X-Proforma-Fingerprint"); fp != again.Header.Get("X-Proforma-Fingerprint") {
+		t.Errorf("the tntengine backend gave two machines for the same secret")
+	}
+	m, err := loadProForma(bytes.NewReader(body), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.fingerprintText() != first.Header.Get("X-Proforma-Fingerprint") {
+		t.Errorf("the fingerprint header is not the fingerprint of the machine")
+	}
+}
+
+// TestServeConcurrentIkm generates ikm output from several requests at once,
+// for the race detector.
+func TestServeConcurrentIkm(t *testing.T) {
+	srv := httptest.NewServer(newProformaServer([]string{"random"}, 0, 0))
+	defer srv.Close()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := srv.Client().Post(srv.URL+"/v1/generate", "application/json",
+				strings.NewReader(`{"outputType": "ikm"}`))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if _, err := loadProForma(bytes.NewReader(body), "ikm"); err != nil {
+				t.Errorf("the ikm output does not load: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestServeVerify checks the status codes of /v1/verify.
+func TestServeVerify(t *testing.T) {
+	srv := httptest.NewServer(newProformaServer([]string{"random"}, 0, 0))
+	defer srv.Close()
+	m, err := generateMachine(goldenSource(), engineLayout, rotorSizes, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var valid, ikm bytes.Buffer
+	if err := writeProForma(&valid, "json", m); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeProForma(&ikm, "ikm", m); err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := m.fingerprintText()
+	m.Rotors[0].Start = m.Rotors[0].Size
+	var invalid bytes.Buffer
+	if err := writeProForma(&invalid, "json", m); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		valid  bool
+	}{
+		{"valid", "/v1/verify", valid.String(), http.StatusOK, true},
+		{"ikm", "/v1/verify?type=ikm", ikm.String(), http.StatusOK, true},
+		{"invalid", "/v1/verify", invalid.String(), http.StatusUnprocessableEntity, false},
+		{"not a machine", "/v1/verify", `{"Size": 1}`, http.StatusUnprocessableEntity, false},
+		{"input type", "/v1/verify?type=c", valid.String(), http.StatusBadRequest, false},
+		{"too large", "/v1/verify", "[" + strings.Repeat(" ", maxRequestBytes) + "]", http.StatusRequestEntityTooLarge, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := post(t, srv, tc.path, tc.body)
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tc.status, body)
+			}
+			if tc.status == http.StatusBadRequest {
+				return
+			}
+			var res verifyResponse
+			if err := json.Unmarshal(body, &res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Valid != tc.valid || (len(res.Errors) == 0) != tc.valid {
+				t.Errorf("got %+v, want valid = %v", res, tc.valid)
+			}
+			if tc.valid && res.Fingerprint != fingerprint {
+				t.Errorf("fingerprint = %s, want %s", res.Fingerprint, fingerprint)
+			}
+		})
+	}
+}
+
+// TestRateLimiter checks the token buckets with a fake clock.
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+	for i := range 3 {
+		if _, ok := l.allow("a"); !ok {
+			t.Fatalf("request %d of the burst was limited", i+1)
+		}
+	}
+	wait, ok := l.allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("allow after the burst = %v, %v, want 500ms, false", wait, ok)
+	}
+	if _, ok := l.allow("b"); !ok {
+		t.Errorf("a second client shares the first client's bucket")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if _, ok := l.allow("a"); !ok {
+		t.Errorf("the bucket was not refilled")
+	}
+	if _, ok := l.allow("a"); ok {
+		t.Errorf("the bucket was refilled too much")
+	}
+	// Idle clients are forgotten once their buckets are full again.
+	now = now.Add(time.Hour)
+	l.allow("c")
+	if len(l.buckets) != 1 {
+		t.Errorf("%d buckets are kept, want 1", len(l.buckets))
+	}
+}
+
+// TestServeRateLimitAndMetrics checks that the server limits requests, but
+// not the health check or the metrics, and counts them in /metrics.
+func TestServeRateLimitAndMetrics(t *testing.T) {
+	srv := httptest.NewServer(newProformaServer([]string{"random"}, 0.001, 1))
+	defer srv.Close()
+	if resp, body := post(t, srv, "/v1/generate", `{}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	resp, _ := post(t, srv, "/v1/generate", `{}`)
+	if resp.StatusCode != http.StatusTooManyRequests || len(resp.Header.Get("Retry-After")) == 0 {
+		t.Fatalf("status = %d, Retry-After = %q, want 429 with Retry-After",
+			resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	for _, path := range []string{"/healthz", "/healthz", "/metrics"} {
+		resp, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	metrics, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`genproforma_http_requests_total{pattern="POST /v1/generate",code="200"} 1`,
+		`genproforma_http_requests_total{pattern="POST /v1/generate",code="429"} 1`,
+		`genproforma_http_requests_total{pattern="GET /healthz",code="200"} 2`,
+		`genproforma_machines_generated_total{backend="random"} 1`,
+		`genproforma_rate_limited_total 1`,
+	} {
+		if !strings.Contains(string(metrics), want+"\n") {
+			t.Errorf("the metrics do not contain %s:\n%s", want, metrics)
+		}
+	}
+}
+
+// testCert creates a certificate for name signed by parent (self-signed if
+// parent is nil), returning it with its key.
+func testCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// writePEM writes the certificate, and the key if it is not nil, to the named
+// PEM file.
+func writePEM(t *testing.T, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) {
+	t.Helper()
+	var out bytes.Buffer
+	pem.Encode(&out, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if key != nil {
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pem.Encode(&out, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	}
+	if err := os.WriteFile(name, out.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestServeClientIdentity serves with the TLS configuration from the
+// --tls-cert, --tls-key and --client-ca flags, and checks that clients are
+// identified by the common name of their certificates and that clients
+// without one are refused.
+func TestServeClientIdentity(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testCert(t, "test CA", nil, nil)
+	server, serverKey := testCert(t, "server", ca, caKey)
+	writePEM(t, filepath.Join(dir, "ca.pem"), ca, nil)
+	writePEM(t, filepath.Join(dir, "server.pem"), server, serverKey)
+
+	saved := [3]string{tlsCertFile, tlsKeyFile, clientCAFile}
+	defer func() { tlsCertFile, tlsKeyFile, clientCAFile = saved[0], saved[1], saved[2] }()
+	tlsCertFile = filepath.Join(dir, "server.pem")
+	tlsKeyFile = tlsCertFile
+	clientCAFile = filepath.Join(dir, "ca.pem")
+	config, err := serverTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, clientID(r))
+	}))
+	srv.TLS = config
+	// The refused handshakes are expected.
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certs,
+		}}}
+	}
+	for _, name := range []string{"alice", "bob"} {
+		cert, key := testCert(t, name, ca, caKey)
+		resp, err := client(tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(id) != "cn:"+name {
+			t.Errorf("the client was identified as %q, want %q", id, "cn:"+name)
+		}
+	}
+	if resp, err := client().Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("a client without a certificate was served")
+	}
+	// A certificate from another CA is refused as well.
+	other, otherKey := testCert(t, "other CA", nil, nil)
+	cert, key := testCert(t, "mallory", other, otherKey)
+	if resp, err := client(tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}).Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("a client with a certificate from another CA was served")
+	}
+
+	if id := peerID(nil, "192.0.2.1:1234"); id != "192.0.2.1" {
+		t.Errorf("a client without TLS was identified as %q, want its address", id)
+	}
+}
+
+// TestCheckServeBackends checks that only the server-safe sources can be
+// served.
+func TestCheckServeBackends(t *testing.T) {
+	saved := serveBackends
+	defer func() { serveBackends = saved }()
+	for _, name := range Sources() {
+		serveBackends = []string{"random", name}
+		err := checkServeBackends()
+		if safe := slices.Contains(serverSources, name); (err == nil) != safe {
+			t.Errorf("%s: checkServeBackends() = %v, server-safe = %v", name, err, safe)
+		}
+	}
+	for _, name := range []string{"ceremony", "recover", "dice", "file"} {
+		if slices.Contains(serverSources, name) {
+			t.Errorf("the %s source can be served", name)
+		}
+	}
+	for _, backends := range [][]string{nil, {"no-such-source"}} {
+		serveBackends = backends
+		if err := checkServeBackends(); err == nil {
+			t.Errorf("the backends %q were accepted", backends)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Shamir's secret sharing over GF(2^8), using the AES polynomial
+// x^8 + x^4 + x^3 + x + 1.  Each byte of the secret is the constant term of a
+// random polynomial of degree k-1, and share x holds the polynomials evaluated
+// at x.  Any k shares recover the secret with Lagrange interpolation at 0;
+// fewer than k shares reveal nothing about it.
+
+// gfExp and gfLog are the exponent and logarithm tables for the generator 3.
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := range 255 {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		// Multiply x by 3: x*2 + x, reducing by the AES polynomial.
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if b == 0 {
+		panic("division by zero in GF(2^8)")
+	}
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// share is one of the shares of a secret.
+type share struct {
+	id        string // identifies the secret the share belongs to
+	threshold int    // the number of shares needed to recover the secret
+	x         byte   // the point the share was evaluated at (1 - 255)
+	data      []byte
+}
+
+// splitSecret splits the secret into n shares, any k of which recover it.
+// The coefficients of the polynomials are read from rnd.
+func splitSecret(secret []byte, k, n int, id string, rnd io.Reader) ([]share, error) {
+	if k < 2 || k > n || n > 255 {
+		return nil, fmt.Errorf("cannot split a secret into %d shares with a threshold of %d", n, k)
+	}
+	shares := make([]share, n)
+	for i := range shares {
+		shares[i] = share{id: id, threshold: k, x: byte(i + 1), data: make([]byte, len(secret))}
+	}
+	coef := make([]byte, k)
+	for j, s := range secret {
+		if _, err := io.ReadFull(rnd, coef[1:]); err != nil {
+			return nil, err
+		}
+		coef[0] = s
+		for i := range shares {
+			// Evaluate the polynomial at x with Horner's method.
+			var y byte
+			for c := k - 1; c >= 0; c-- {
+				y = gfMul(y, shares[i].x) ^ coef[c]
+			}
+			shares[i].data[j] = y
+		}
+	}
+	return shares, nil
+}
+
+// combineShares recovers the secret from the shares.  There must be at least
+// as many shares as the threshold, all from the same secret.
+func combineShares(shares []share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares were given")
+	}
+	first := shares[0]
+	if len(shares) < first.threshold {
+		return nil, fmt.Errorf("%d shares are needed, only %d were given", first.threshold, len(shares))
+	}
+	shares = shares[:first.threshold]
+	seen := make(map[byte]bool)
+	for _, s := range shares {
+		if s.id != first.id || s.threshold != first.threshold || len(s.data) != len(first.data) {
+			return nil, fmt.Errorf("share %d is not from the same secret as share %d", s.x, first.x)
+		}
+		if seen[s.x] {
+			return nil, fmt.Errorf("share %d was given more than once", s.x)
+		}
+		seen[s.x] = true
+	}
+	secret := make([]byte, len(first.data))
+	for i, si := range shares {
+		// The Lagrange basis polynomial for share i evaluated at 0.  In
+		// GF(2^8) subtraction is addition (xor).
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = gfMul(basis, gfDiv(sj.x, sj.x^si.x))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= gfMul(si.data[b], basis)
+		}
+	}
+	return secret, nil
+}
+
+// shareTag starts every encoded share.
+const shareTag = "gpf1"
+
+// String encodes the share as
+//
+//	gpf1-<id>-<threshold>-<x>-<data>-<checksum>
+//
+// where the data is hex encoded and the checksum is the first 4 bytes of the
+// SHA-256 hash of the rest of the share, so mistyped shares are caught.
+func (s share) String() string {
+	body := fmt.Sprintf("%s-%s-%d-%d-%s", shareTag, s.id, s.threshold, s.x, hex.EncodeToString(s.data))
+	return body + "-" + shareChecksum(body)
+}
+
+// shareChecksum returns the checksum of the body of an encoded share.
+func shareChecksum(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:4])
+}
+
+// parseShare decodes a share encoded by String.  Spaces are ignored and case
+// does not matter.
+func parseShare(text string) (share, error) {
+	text = strings.ToLower(strings.Join(strings.Fields(text), ""))
+	fields := strings.Split(text, "-")
+	if len(fields) != 6 || fields[0] != shareTag {
+		return share{}, errors.New("not a genProforma share")
+	}
+	if shareChecksum(strings.Join(fields[:5], "-")) != fields[5] {
+		return share{}, errors.New("the share checksum does not match, check for typing mistakes")
+	}
+	k, err := strconv.Atoi(fields[2])
+	if err != nil || k < 2 || k > 255 {
+		return share{}, fmt.Errorf("%q is not a valid threshold", fields[2])
+	}
+	x, err := strconv.Atoi(fields[3])
+	if err != nil || x < 1 || x > 255 {
+		return share{}, fmt.Errorf("%q is not a valid share number", fields[3])
+	}
+	data, err := hex.DecodeString(fields[4])
+	if err != nil {
+		return share{}, err
+	}
+	return share{id: fields[1], threshold: k, x: byte(x), data: data}, nil
+}
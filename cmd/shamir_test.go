@@ -0,0 +1,235 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// slowMul multiplies in GF(2^8) bit by bit.
+func slowMul(a, b byte) byte {
+	var res byte
+	for ; b != 0; b >>= 1 {
+		if b&1 != 0 {
+			res ^= a
+		}
+		hi := a & 0x80
+		a <<= 1
+		if hi != 0 {
+			a ^= 0x1b
+		}
+	}
+	return res
+}
+
+func TestGF(t *testing.T) {
+	for a := range 256 {
+		for b := range 256 {
+			p := gfMul(byte(a), byte(b))
+			if want := slowMul(byte(a), byte(b)); p != want {
+				t.Fatalf("gfMul(%#02x, %#02x) = %#02x, want %#02x", a, b, p, want)
+			}
+			if b != 0 && gfDiv(p, byte(b)) != byte(a) {
+				t.Fatalf("gfDiv(%#02x, %#02x) = %#02x, want %#02x", p, b, gfDiv(p, byte(b)), a)
+			}
+		}
+	}
+}
+
+// subsets calls f with every subset of k of the shares.
+func subsets(shares []share, k int, f func([]share)) {
+	var pick func(start int, chosen []share)
+	pick = func(start int, chosen []share) {
+		if len(chosen) == k {
+			f(chosen)
+			return
+		}
+		for i := start; i < len(shares); i++ {
+			pick(i+1, append(chosen[:len(chosen):len(chosen)], shares[i]))
+		}
+	}
+	pick(0, nil)
+}
+
+// TestShamirRoundTrip splits secrets and recovers them from every subset of
+// threshold shares, in either order, and checks that every smaller subset is
+// rejected.
+func TestShamirRoundTrip(t *testing.T) {
+	for _, tc := range []struct{ k, n int }{{2, 2}, {2, 3}, {3, 5}, {5, 5}, {4, 7}} {
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		shares, err := splitSecret(secret, tc.k, tc.n, "0badcafe", rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shares) != tc.n {
+			t.Fatalf("%d of %d: got %d shares", tc.k, tc.n, len(shares))
+		}
+		for _, s := range shares {
+			if bytes.Equal(s.data, secret) {
+				t.Errorf("%d of %d: share %d is the secret", tc.k, tc.n, s.x)
+			}
+		}
+		subsets(shares, tc.k, func(some []share) {
+			for _, order := range [][]share{some, reversed(some)} {
+				got, err := combineShares(order)
+				if err != nil {
+					t.Fatalf("%d of %d: %v", tc.k, tc.n, err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Fatalf("%d of %d: shares %v recovered the wrong secret", tc.k, tc.n, shareNumbers(order))
+				}
+			}
+		})
+		subsets(shares, tc.k-1, func(some []share) {
+			if _, err := combineShares(some); err == nil {
+				t.Fatalf("%d of %d: %d shares were accepted", tc.k, tc.n, tc.k-1)
+			}
+		})
+	}
+}
+
+func reversed(shares []share) []share {
+	res := make([]share, len(shares))
+	for i, s := range shares {
+		res[len(shares)-1-i] = s
+	}
+	return res
+}
+
+func shareNumbers(shares []share) []byte {
+	var res []byte
+	for _, s := range shares {
+		res = append(res, s.x)
+	}
+	return res
+}
+
+func TestSplitSecretErrors(t *testing.T) {
+	for _, tc := range []struct{ k, n int }{{1, 3}, {0, 0}, {4, 3}, {2, 256}} {
+		if _, err := splitSecret([]byte("secret"), tc.k, tc.n, "00", rand.Reader); err == nil {
+			t.Errorf("a threshold of %d of %d shares was accepted", tc.k, tc.n)
+		}
+	}
+	if _, err := splitSecret([]byte("secret"), 2, 3, "00", bytes.NewReader(nil)); err == nil {
+		t.Errorf("the secret was split without random coefficients")
+	}
+}
+
+// TestCombineSharesErrors checks that shares which are not from the same
+// secret, or are repeated, are rejected.
+func TestCombineSharesErrors(t *testing.T) {
+	secret := []byte("the master seed")
+	shares, err := splitSecret(secret, 3, 5, "00c0ffee", rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	others, err := splitSecret(secret, 3, 5, "0ddba11a", rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := shares[2]
+	short.data = short.data[1:]
+	for name, bad := range map[string][]share{
+		"none":             nil,
+		"repeated":         {shares[0], shares[1], shares[0]},
+		"other ceremony":   {shares[0], shares[1], others[2]},
+		"other threshold":  {shares[0], shares[1], {id: "00c0ffee", threshold: 2, x: 3, data: shares[2].data}},
+		"different length": {shares[0], shares[1], short},
+	} {
+		if _, err := combineShares(bad); err == nil {
+			t.Errorf("%s: the shares were accepted", name)
+		}
+	}
+
+	// A share with corrupt data cannot be detected by the interpolation, so
+	// it gives a different secret; the checksum of the encoding is what
+	// catches it.
+	corrupt := shares[2]
+	corrupt.data = append([]byte(nil), corrupt.data...)
+	corrupt.data[0] ^= 1
+	got, err := combineShares([]share{shares[0], shares[1], corrupt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got, secret) {
+		t.Errorf("a corrupt share recovered the secret")
+	}
+	fields := strings.Split(shares[2].String(), "-")
+	fields[4] = hex.EncodeToString(corrupt.data)
+	if _, err := parseShare(strings.Join(fields, "-")); err == nil {
+		t.Errorf("a share with corrupt data and the old checksum was accepted")
+	}
+}
+
+// TestParseShare checks that shares read back, and that every single changed
+// character of a share is caught.
+func TestParseShare(t *testing.T) {
+	shares, err := splitSecret([]byte("the master seed"), 2, 3, "00c0ffee", rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range shares {
+		text := s.String()
+		for _, in := range []string{text, strings.ToUpper(text), " " + text[:10] + " " + text[10:] + "\n"} {
+			got, err := parseShare(in)
+			if err != nil {
+				t.Fatalf("%q: %v", in, err)
+			}
+			if got.id != s.id || got.threshold != s.threshold || got.x != s.x || !bytes.Equal(got.data, s.data) {
+				t.Fatalf("%q read back as %+v, want %+v", in, got, s)
+			}
+		}
+	}
+
+	text := shares[0].String()
+	for i := range text {
+		for _, c := range "0af-g" {
+			if byte(c) == text[i] {
+				continue
+			}
+			typo := text[:i] + string(c) + text[i+1:]
+			if _, err := parseShare(typo); err == nil {
+				t.Errorf("the mistyped share %q was accepted", typo)
+			}
+		}
+	}
+
+	// Shares with a valid checksum and a bad field.
+	for _, body := range []string{
+		"gpf1-00c0ffee-1-1-00",
+		"gpf1-00c0ffee-256-1-00",
+		"gpf1-00c0ffee-x-1-00",
+		"gpf1-00c0ffee-2-0-00",
+		"gpf1-00c0ffee-2-256-00",
+		"gpf1-00c0ffee-2-1-0g",
+		"gpf1-00c0ffee-2-1-000",
+	} {
+		in := body + "-" + shareChecksum(body)
+		if _, err := parseShare(in); err == nil {
+			t.Errorf("the share %q was accepted", in)
+		}
+	}
+	for _, in := range []string{"", "gpf1", "gpf2-00c0ffee-2-1-00-00000000", "gpf1-00c0ffee-2-1-00-00000000-00"} {
+		if _, err := parseShare(in); err == nil {
+			t.Errorf("%q was accepted as a share", in)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// Source is a source of the random data used to create a proforma machine.
+// Int63n must return a uniformly distributed value in [0, n) and Perm a
+// uniformly distributed permutation of [0, n).  NewStreamSource creates a
+// Source with unbiased sampling from a stream of random bytes.
+type Source interface {
+	Read(p []byte) (n int, err error)
+	Int63n(n int64) (int64, error)
+	Perm(n int) ([]int, error)
+}
+
+// ConcurrentSource is implemented by a Source that may report its methods as
+// safe to call from multiple goroutines at the same time.  A Source that does
+// not implement it is used from one goroutine, in order.
+type ConcurrentSource interface {
+	Source
+	Concurrent() bool
+}
+
+// isConcurrent reports whether the source is safe for concurrent use.
+func isConcurrent(s Source) bool {
+	c, ok := s.(ConcurrentSource)
+	return ok && c.Concurrent()
+}
+
+// closeSource closes the source if it holds a resource, such as the entropy
+// file of the file source, that must be released when it is done.
+func closeSource(s Source) error {
+	if c, ok := s.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// SourceFunc creates a Source from the command line arguments.
+type SourceFunc func(args []string) (Source, error)
+
+// sourceRegistry holds the named sources.  Each backend registers its source
+// in its init function.
+var sourceRegistry = make(map[string]SourceFunc)
+
+// sourceAnnotation is the annotation naming the source used by a command
+// that generates proforma machines.
+const sourceAnnotation = "source"
+
+var (
+	// source is the Source used to create the proforma machine, and sourceName
+	// the name it is registered under.  They are set by bindSource when a
+	// command is run.
+	source     Source
+	sourceName string
+)
+
+// RegisterSource makes a source available by name.  It panics if the name is
+// already registered.
+func RegisterSource(name string, newSource SourceFunc) {
+	if _, dup := sourceRegistry[name]; dup {
+		panic("RegisterSource called twice for source " + name)
+	}
+	sourceRegistry[name] = newSource
+}
+
+// Sources returns the sorted names of the registered sources.
+func Sources() []string {
+	names := make([]string, 0, len(sourceRegistry))
+	for name := range sourceRegistry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewStreamSource returns a Source that reads its random data from read,
+// using rejection sampling for Int63n and Perm.
+func NewStreamSource(read func([]byte) (int, error)) Source {
+	return sampler{read: read}
+}
+
+// NewConcurrentStreamSource returns a Source like NewStreamSource for a read
+// function that is safe for concurrent use, such as crypto/rand.Read.
+func NewConcurrentStreamSource(read func([]byte) (int, error)) Source {
+	return sampler{read: read, concurrent: true}
+}
+
+// newSource creates the named source from the command line arguments.
+func newSource(name string, args []string) (Source, error) {
+	newSource, ok := sourceRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a registered source", name)
+	}
+	return newSource(args)
+}
+
+// bindSource makes the source named by the command's source annotation the
+// source used to create the proforma machine.
+func bindSource(cmd *cobra.Command, args []string) error {
+	name, ok := cmd.Annotations[sourceAnnotation]
+	if !ok {
+		return fmt.Errorf("the %s command does not declare a source", cmd.Name())
+	}
+	return useSource(name, args)
+}
+
+// useSource makes the named source, created from the arguments, the source
+// used to create the proforma machine.
+func useSource(name string, args []string) error {
+	s, err := newSource(name, args)
+	if err != nil {
+		return withExitCode(exitEntropy, fmt.Errorf("starting the %s source: %w", name, err))
+	}
+	source, sourceName = s, name
+	return nil
+}
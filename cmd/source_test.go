@@ -0,0 +1,175 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestSubcommandSources runs each generating subcommand and checks that it
+// binds its declared source.  For the deterministic sources the generated
+// machine is also compared with one created directly from the source, so a
+// subcommand cannot silently use a different source.
+func TestSubcommandSources(t *testing.T) {
+	dir := t.TempDir()
+	entropy := filepath.Join(dir, "entropy.bin")
+	data := make([]byte, 64<<10)
+	rand.Read(data)
+	if err := os.WriteFile(entropy, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rolls := filepath.Join(dir, "rolls.txt")
+	if err := os.WriteFile(rolls, []byte(strings.Repeat("1 2 3 4 5 6 6 5 4 3 2 1\n", 40)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	shares, err := splitSecret(bytes.Repeat([]byte{7}, 32), 2, 3, "00c0ffee", rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type sourceTest struct {
+		command       string
+		source        string
+		args          []string
+		stdin         string
+		deterministic bool
+		concurrent    bool
+	}
+	tests := []sourceTest{
+		{"random", "random", nil, "", false, true},
+		{"tntengine", "tntengine", []string{"matrix", "secret"}, "", true, false},
+		{"ikmachine", "ikmachine", []string{"matrix", "secret"}, "", true, false},
+		{"file", "file", []string{entropy}, "", true, false},
+		{"dice", "dice", nil, rolls, true, false},
+		{"mixed", "mixed", []string{"matrix", "secret"}, "", false, false},
+		{"ceremony", "ceremony", nil, "", false, false},
+		{"recover", "recover", []string{shares[2].String(), shares[0].String()}, "", true, false},
+	}
+
+	// Every command that declares a source must be in the matrix.
+	for _, c := range rootCmd.Commands() {
+		name, ok := c.Annotations[sourceAnnotation]
+		if !ok {
+			continue
+		}
+		if !slices.Contains(Sources(), name) {
+			t.Errorf("the %s command declares the unregistered source %s", c.Name(), name)
+		}
+		if !slices.ContainsFunc(tests, func(tc sourceTest) bool {
+			return tc.command == c.Name()
+		}) {
+			t.Errorf("the %s command is not tested", c.Name())
+		}
+	}
+
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	for _, tc := range tests {
+		t.Run(tc.command, func(t *testing.T) {
+			source, sourceName = nil, ""
+			if tc.stdin != "" {
+				f, err := os.Open(tc.stdin)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				os.Stdin = f
+			}
+			out := filepath.Join(dir, tc.command+".json")
+			rootCmd.SetArgs(append([]string{tc.command, "-t", "json", "-f", out}, tc.args...))
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			// The command closes its source; closing it again is harmless.
+			defer closeSource(source)
+			if sourceName != tc.source {
+				t.Fatalf("the %s command used the %q source, want %q", tc.command, sourceName, tc.source)
+			}
+			if isConcurrent(source) != tc.concurrent {
+				t.Errorf("the %s source is concurrent = %v, want %v", tc.source, !tc.concurrent, tc.concurrent)
+			}
+			if !tc.deterministic {
+				return
+			}
+			got, err := loadProFormaFile(out, "json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.stdin != "" {
+				f, err := os.Open(tc.stdin)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				os.Stdin = f
+			}
+			source, err = newSource(tc.source, tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeSource(source)
+			want, err := newMachine()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.fingerprint() != want.fingerprint() {
+				t.Errorf("the %s command did not generate the machine from the %s source", tc.command, tc.source)
+			}
+		})
+	}
+}
+
+// hmacSourceName is a source registered by the tests, with no command of
+// its own, that makes its stream from the HMAC key given as its argument.
+const hmacSourceName = "test-hmac"
+
+func init() {
+	RegisterSource(hmacSourceName, func(args []string) (Source, error) {
+		return NewStreamSource(newHmacStream([]byte(strings.Join(args, " "))).Read), nil
+	})
+}
+
+// TestGenerateSource checks that the generate command uses any registered
+// source.
+func TestGenerateSource(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "generate.json")
+	var stderr bytes.Buffer
+	jsonErrors = false
+	if code := run([]string{"generate", "-t", "json", "-f", out, hmacSourceName, "a", "key"}, &stderr); code != exitOK {
+		t.Fatalf("exit code = %d (%s)", code, stderr.String())
+	}
+	if sourceName != hmacSourceName {
+		t.Errorf("the generate command used the %q source, want %q", sourceName, hmacSourceName)
+	}
+	got, err := loadProFormaFile(out, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := generateMachine(NewStreamSource(newHmacStream([]byte("a key")).Read), engineLayout, rotorSizes, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.fingerprint() != want.fingerprint() {
+		t.Errorf("the generate command did not pass the arguments to the source")
+	}
+}
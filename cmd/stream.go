@@ -0,0 +1,52 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// hmacStream is a deterministic stream of pseudo-random bytes created by
+// running HMAC-SHA256, keyed with a seed, over an incrementing counter.
+type hmacStream struct {
+	key     []byte
+	counter uint64
+	blk     []byte // unused bytes from the last block
+}
+
+// newHmacStream returns a stream keyed with the given seed.
+func newHmacStream(seed []byte) *hmacStream {
+	return &hmacStream{key: append([]byte(nil), seed...)}
+}
+
+// Read fills p with the next bytes from the stream.  It never fails.
+func (s *hmacStream) Read(p []byte) (n int, err error) {
+	mac := hmac.New(sha256.New, s.key)
+	for n < len(p) {
+		if len(s.blk) == 0 {
+			mac.Reset()
+			mac.Write(binary.BigEndian.AppendUint64(nil, s.counter))
+			s.blk = mac.Sum(nil)
+			s.counter++
+		}
+		cnt := copy(p[n:], s.blk)
+		s.blk = s.blk[cnt:]
+		n += cnt
+	}
+	return n, nil
+}
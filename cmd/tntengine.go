@@ -16,79 +16,74 @@ limitations under the License.
 package cmd
 
 import (
-	"fmt"
-	"os"
-	"strings"
+	"sync"
 
 	"github.com/bgallie/tntengine"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"golang.org/x/term"
-)
-
-var (
-	tntMachine tntengine.TntEngine
-	random     *tntengine.Rand
 )
 
 // tntengineCmd represents the tntengine command
 var tntengineCmd = &cobra.Command{
 	Use:   "tntengine",
 	Short: "Generate a new proforma machine",
-	Long:  `Generate a new proforma machine using a psudo-random number generator (tntengine).`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if rootCmd.Flags().Changed("outputType") {
-			if outputType != "json" && outputType != "ikm" {
-				cobra.CheckErr(outputType + " is not a valid output type.")
-			}
-		} else {
-			rootCmd.Flags().Set("outputType", "json")
-		}
-		initEngine(args)
-		generateProForma(outputType)
+	Long: `Generate a new proforma machine using a psudo-random number generator (tntengine).
+
+The rotor and permutator values are chosen from the random bytes with
+rejection sampling, so they are uniformly distributed.  Releases before
+the rejection sampler used the engine's own Int63n and Perm, so the same
+passphrase now makes a different machine; use --legacy-sampling to make the
+machine those releases made.`,
+	Annotations: map[string]string{sourceAnnotation: "tntengine"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return generateCommand(cmd, args, "json")
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(tntengineCmd)
+	tntengineCmd.Flags().StringVar(&label, "label", "", `Derive the key from the passphrase (the master secret) and this label
+	using HKDF, so each label reproduces its own machine.`)
+	tntengineCmd.Flags().BoolVar(&legacySampling, "legacy-sampling", false, `Sample with the engine's own Int63n and Perm, as releases before the
+	rejection sampler did, to make again a machine generated from the same
+	passphrase by those releases.  The engine's Perm is Sattolo's algorithm, so
+	every randp of such a machine is a single cycle through all 256 values.`)
+	RegisterSource("tntengine", newTntSource)
 }
 
-func initEngine(args []string) {
-	// Obtain the passphrase used to encrypt the file from either:
-	// 1. User input from the terminal (most secure)
-	// 2. The 'TNT2_SECRET' environment variable (less secure)
-	// 3. Arguments from the entered command line (least secure - not recommended)
-	var secret string
-	if len(args) == 0 {
-		if viper.IsSet("GPF_SECRET") {
-			secret = viper.GetString("GPF_SECRET")
-		} else {
-			if term.IsTerminal(int(os.Stdin.Fd())) {
-				fmt.Fprintf(os.Stderr, "Enter the passphrase: ")
-				byteSecret, err := term.ReadPassword(int(os.Stdin.Fd()))
-				cobra.CheckErr(err)
-				fmt.Fprintln(os.Stderr, "")
-				secret = string(byteSecret)
-			}
-		}
-	} else {
-		secret = strings.Join(args, " ")
-	}
-
-	if len(secret) == 0 {
-		cobra.CheckErr("You must supply a password.")
-		// } else {
-		// 	fmt.Printf("Secret: [%s]\n", secret)
+// newTntSource returns a Source that reads the stream of pseudo-random bytes
+// from a tntengine keyed with the secret.
+func newTntSource(args []string) (Source, error) {
+	secret, err := getSecret(args)
+	if err != nil {
+		return nil, err
 	}
 
 	// Initialize the tntengine with the secret key and the named proforma file.
+	var tntMachine tntengine.TntEngine
 	tntMachine.Init([]byte(secret))
 	tntMachine.SetEngineType("E")
 	// Now the the engine type is set, build the cipher machine.
 	tntMachine.BuildCipherMachine()
-	// Get the random functions
-	random = new(tntengine.Rand).New(&tntMachine)
-	rRead = random.Read
-	rPerm = random.Perm
-	rInt = random.Int63n
+	// The engine's own Int63n and Perm are not used, unless asked for, so
+	// that all of the sources are sampled the same way.
+	random := new(tntengine.Rand).New(&tntMachine)
+	s := &tntSource{Source: NewStreamSource(random.Read), engine: &tntMachine}
+	if legacySampling {
+		s.Source = engineSampler{read: random.Read, int63n: random.Int63n, perm: random.Perm}
+	}
+	return s, nil
+}
+
+// tntSource is the Source of a keyed tntengine.  Closing it shuts down the
+// goroutines of the engine's cipher machine.
+type tntSource struct {
+	Source
+	engine *tntengine.TntEngine
+	once   sync.Once
+}
+
+// Close shuts down the cipher machine.  Only the first call has any effect.
+func (s *tntSource) Close() error {
+	s.once.Do(s.engine.CloseCipherMachine)
+	return nil
 }
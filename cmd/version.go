@@ -93,14 +93,18 @@ func init() {
 	// Get the build date (as the modified date of the executable) if the build date
 	// is not set.
 	if BuildDate == "not set" {
+		// The build date is only informational, so it is left unset if the
+		// executable cannot be found.
 		fpath, err := os.Executable()
-		cobra.CheckErr(err)
-		fpath, err = filepath.EvalSymlinks(fpath)
-		cobra.CheckErr(err)
-		fsys := os.DirFS(filepath.Dir(fpath))
-		fInfo, err := fs.Stat(fsys, filepath.Base(fpath))
-		cobra.CheckErr(err)
-		BuildDate = fInfo.ModTime().UTC().Format(time.RFC3339)
+		if err == nil {
+			fpath, err = filepath.EvalSymlinks(fpath)
+		}
+		if err == nil {
+			fsys := os.DirFS(filepath.Dir(fpath))
+			if fInfo, err := fs.Stat(fsys, filepath.Base(fpath)); err == nil {
+				BuildDate = fInfo.ModTime().UTC().Format(time.RFC3339)
+			}
+		}
 	}
 	rootCmd.AddCommand(versionCmd)
 }
@@ -0,0 +1,354 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// sourceDescriptions describes the sources offered by the wizard.
+var sourceDescriptions = map[string]string{
+	"random":    "Go's cryptographically secure random number generator",
+	"tntengine": "a tntengine keyed with a passphrase (reproducible)",
+	"ikmachine": "an ikmachine keyed with a passphrase (reproducible)",
+	"mixed":     "crypto/rand mixed with other entropy sources",
+	"dice":      "dice rolls or coin flips entered by the operator",
+	"file":      "a file of random data, such as a hardware RNG dump",
+	"ceremony":  "a master seed split into shares for a key ceremony",
+	"recover":   "the master seed of a key ceremony rebuilt from its shares",
+}
+
+// outputDescriptions describes the output types offered by the wizard.
+var outputDescriptions = map[string]string{
+	"json":   "JSON, read by the convert, diff and inspect commands",
+	"ikm":    "Go source for the proforma machine in ikmachine",
+	"c":      "a C header",
+	"rust":   "a Rust module",
+	"python": "a Python module",
+}
+
+// wizardCmd represents the wizard command
+var wizardCmd = &cobra.Command{
+	Use:   "wizard",
+	Short: "Interactively choose the options and generate a new proforma machine",
+	Long: `Walk through generating a new proforma machine: choose the backend, the
+layout, the rotor and cycle sizes, the output type and where to write it.  The
+size of the state space and the fingerprint of the machine are shown before it
+is written, and nothing is written unless the machine is accepted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWizard(&wizard{in: os.Stdin, out: os.Stderr})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(wizardCmd)
+}
+
+// wizard asks the operator questions and reads the answers.
+type wizard struct {
+	in  io.Reader
+	out io.Writer
+	buf *bufio.Reader
+}
+
+// readLine reads a line of input.  Stdin is read through the reader shared
+// with the sources that read lines from stdin themselves, so that the lines
+// buffered by one are not lost to the other.
+func (w *wizard) readLine() (string, error) {
+	if w.buf == nil {
+		if w.in == io.Reader(os.Stdin) {
+			w.buf = stdinReader()
+		} else {
+			w.buf = bufio.NewReader(w.in)
+		}
+	}
+	line, err := readLine(w.buf)
+	return strings.TrimSpace(line), err
+}
+
+// ask asks the question until the answer is accepted by check.  An empty
+// answer selects the default, if there is one.
+func (w *wizard) ask(question, def string, check func(string) error) (string, error) {
+	for {
+		if len(def) != 0 {
+			fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(w.out, "%s: ", question)
+		}
+		answer, err := w.readLine()
+		if err != nil {
+			return "", fmt.Errorf("the wizard was not finished: %w", err)
+		}
+		if len(answer) == 0 {
+			answer = def
+		}
+		if err = check(answer); err == nil {
+			return answer, nil
+		}
+		fmt.Fprintln(w.out, " ", err)
+	}
+}
+
+// choose lists the choices and asks for one, by number or by name.
+func (w *wizard) choose(question string, choices []string, descriptions map[string]string, def string) (string, error) {
+	fmt.Fprintln(w.out, question)
+	for i, c := range choices {
+		fmt.Fprintf(w.out, "  %d) %-10s %s\n", i+1, c, descriptions[c])
+	}
+	answer, err := w.ask("Choice", def, func(s string) error {
+		if n, err := strconv.Atoi(s); (err == nil && n >= 1 && n <= len(choices)) || slices.Contains(choices, s) {
+			return nil
+		}
+		return fmt.Errorf("choose a number from 1 to %d or one of the names", len(choices))
+	})
+	if err != nil {
+		return "", err
+	}
+	if n, err := strconv.Atoi(answer); err == nil {
+		return choices[n-1], nil
+	}
+	return answer, nil
+}
+
+// secret asks for the passphrase for a keyed backend, without echoing it if
+// the input is a terminal.
+func (w *wizard) secret() (string, error) {
+	fmt.Fprint(w.out, "Passphrase: ")
+	if f, ok := w.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		secret, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(w.out, "")
+		return string(secret), err
+	}
+	return w.readLine()
+}
+
+func runWizard(w *wizard) error {
+	backend, err := w.choose("Which backend should create the machine?", Sources(), sourceDescriptions, "random")
+	if err != nil {
+		return err
+	}
+	var args []string
+	switch {
+	case backend == "file":
+		name, err := w.ask("Entropy file", "", func(s string) error {
+			_, err := os.Stat(s)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		args = []string{name}
+	case slices.Contains(keyedSources, backend):
+		label, err := w.ask("Label (empty for none)", "", func(string) error { return nil })
+		if err != nil {
+			return err
+		}
+		secret := viper.GetString("GPF_SECRET")
+		if !viper.IsSet("GPF_SECRET") {
+			if secret, err = w.secret(); err != nil {
+				return err
+			}
+		}
+		// The key is derived here, as the source only sees the --label flag.
+		if len(secret) != 0 && len(label) != 0 {
+			secret = labelKey(secret, label)
+		}
+		args = []string{secret}
+	}
+
+	layout, err := w.ask("Layout of rotors (r) and permutators (p)", engineLayout, func(s string) error {
+		if strings.Trim(s, "rp") != "" || !strings.Contains(s, "r") || !strings.Contains(s, "p") {
+			return errors.New("the layout may only contain r and p, and needs at least one of each")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rCnt := strings.Count(layout, "r")
+	def := ""
+	if rCnt <= len(rotorSizes) {
+		def = joinSizes(rotorSizes[:rCnt])
+	}
+	answer, err := w.ask(fmt.Sprintf("Sizes of the %d rotors", rCnt), def, func(s string) error {
+		_, err := parseRotorSizes(s, rCnt)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	rotors, _ := parseRotorSizes(answer, rCnt)
+	answer, err = w.ask("Cycle sizes of the permutators", joinSizes(cycleSizes), func(s string) error {
+		_, err := parseCycleSizes(s)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	cycles, _ := parseCycleSizes(answer)
+
+	oDef := "json"
+	if backend == "ikmachine" {
+		oDef = "ikm"
+	}
+	oType, err := w.choose("Which output type should be written?", outputTypes, outputDescriptions, oDef)
+	if err != nil {
+		return err
+	}
+	dest, err := w.ask("Write the machine to (- for stdout)", "proforma"+outputExtensions[oType], func(s string) error {
+		if s == "-" {
+			return nil
+		}
+		if _, err := os.Stat(s); err == nil {
+			return fmt.Errorf("%s already exists", s)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if source, err = newSource(backend, args); err != nil {
+		return err
+	}
+	defer closeSource(source)
+	sourceName = backend
+	m, err := generateMachine(source, layout, rotors, cycles)
+	if err != nil {
+		return err
+	}
+	states := machineMaximalStates(m)
+	period := machinePeriod(m)
+	fmt.Fprintln(w.out, "")
+	fmt.Fprintf(w.out, "Backend:      %s\n", backend)
+	fmt.Fprintf(w.out, "Layout:       %s\n", layout)
+	fmt.Fprintf(w.out, "Rotor sizes:  %s\n", joinSizes(rotors))
+	fmt.Fprintf(w.out, "Cycle sizes:  %s\n", joinSizes(cycles))
+	fmt.Fprintf(w.out, "State space:  %s (about 2^%d)\n", states, states.BitLen()-1)
+	fmt.Fprintf(w.out, "Period:       %s (about 2^%d)\n", period, period.BitLen()-1)
+	if period.Cmp(states) < 0 {
+		fmt.Fprintln(w.out, "              The periods of the components share factors, so the period is shorter than the state space.")
+	}
+	fmt.Fprintf(w.out, "Output:       %s to %s\n", oType, dest)
+	fmt.Fprintf(w.out, "Fingerprint:  %s\n", m.fingerprintText())
+	answer, err = w.ask("Write the machine? (y/n)", "n", func(s string) error {
+		if s != "y" && s != "n" {
+			return errors.New("answer y or n")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if answer != "y" {
+		fmt.Fprintln(w.out, "Nothing was written.")
+		return nil
+	}
+	outputFile, err := openOutputFile(dest)
+	if err != nil {
+		return err
+	}
+	err = writeProForma(outputFile, oType, m)
+	if outputFile != os.Stdout {
+		if cErr := outputFile.Close(); err == nil {
+			err = cErr
+		}
+	}
+	if err == nil && oType == "json" && outputFile != os.Stdout {
+		err = writeMeta(dest, m)
+	}
+	return err
+}
+
+// joinSizes formats the sizes as a comma separated list.
+func joinSizes(sizes []int16) string {
+	s := make([]string, len(sizes))
+	for i, v := range sizes {
+		s[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(s, ",")
+}
+
+// parseSizes parses a list of sizes separated by commas or spaces.
+func parseSizes(s string) ([]int16, error) {
+	var sizes []int16
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		v, err := strconv.ParseInt(f, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a size", f)
+		}
+		sizes = append(sizes, int16(v))
+	}
+	return sizes, nil
+}
+
+// parseRotorSizes parses the sizes of n rotors.  A rotor and its 256 bit
+// slice must fit in 256 bytes, and a rotor needs at least two bits for a step.
+func parseRotorSizes(s string, n int) ([]int16, error) {
+	sizes, err := parseSizes(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(sizes) != n {
+		return nil, fmt.Errorf("give %d rotor sizes", n)
+	}
+	for _, v := range sizes {
+		if v < 2 || v > 2048-256 {
+			return nil, fmt.Errorf("the rotor size %d is not between 2 and %d", v, 2048-256)
+		}
+	}
+	return sizes, nil
+}
+
+// parseCycleSizes parses the cycle sizes of a permutator.  The cycles must
+// cover all 256 values, and the product of the sizes must fit in
+// maximalStates.
+func parseCycleSizes(s string) (CycleSizes, error) {
+	sizes, err := parseSizes(s)
+	if err != nil {
+		return nil, err
+	}
+	sum := 0
+	states := big.NewInt(1)
+	for _, v := range sizes {
+		if v < 1 {
+			return nil, fmt.Errorf("the cycle size %d is not positive", v)
+		}
+		sum += int(v)
+		states.Mul(states, big.NewInt(int64(v)))
+	}
+	if sum != 256 {
+		return nil, fmt.Errorf("the cycle sizes add up to %d instead of 256", sum)
+	}
+	if states.Cmp(big.NewInt(math.MaxInt32)) > 0 {
+		return nil, fmt.Errorf("the product of the cycle sizes, %s, is too large", states)
+	}
+	return sizes, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// scriptWizard runs the wizard with the lines of the script as its input,
+// returning what it wrote to the operator.
+func scriptWizard(t *testing.T, script ...string) string {
+	t.Helper()
+	savedSource, savedName := source, sourceName
+	t.Cleanup(func() { source, sourceName = savedSource, savedName })
+	t.Setenv("GPF_SECRET", "")
+	var out bytes.Buffer
+	if err := runWizard(&wizard{in: strings.NewReader(strings.Join(script, "\n") + "\n"), out: &out}); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	return out.String()
+}
+
+func TestWizardKeyed(t *testing.T) {
+	rotors, cycles, savedLabel := slices.Clone(rotorSizes), slices.Clone(cycleSizes), label
+	dest := filepath.Join(t.TempDir(), "wizard.json")
+	out := scriptWizard(t,
+		"tntengine",  // backend
+		"alice",      // label
+		"secret",     // passphrase
+		"rpr",        // layout
+		"1777, 1759", // rotor sizes
+		"",           // the default cycle sizes
+		"1",          // json
+		dest,
+		"y")
+
+	got, err := loadProFormaFile(dest, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newSource("tntengine", []string{labelKey("secret", "alice")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeSource(s)
+	want, err := generateMachine(s, "rpr", []int16{1777, 1759}, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.fingerprint() != want.fingerprint() {
+		t.Errorf("the wizard did not write the machine of the labeled key and the sizes")
+	}
+	for _, line := range []string{"Backend:      tntengine", "Layout:       rpr", "Rotor sizes:  1777,1759",
+		"Fingerprint:  " + want.fingerprintText()} {
+		if !strings.Contains(out, line) {
+			t.Errorf("the summary does not have %q:\n%s", line, out)
+		}
+	}
+	if _, err = os.Stat(metaFileName(dest)); err != nil {
+		t.Errorf("the metadata was not written: %v", err)
+	}
+	if !slices.Equal(rotorSizes, rotors) || !slices.Equal(cycleSizes, cycles) || label != savedLabel {
+		t.Errorf("the wizard changed the rotor sizes, cycle sizes or label")
+	}
+}
+
+// TestWizardDeclined checks that bad answers are asked again and that a
+// machine that is not accepted is not written.
+func TestWizardDeclined(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "wizard.json")
+	out := scriptWizard(t,
+		"99", "random",
+		"rrx", "",
+		"1, 2, 3, 4, 5", "",
+		"60, 60", "",
+		"xml", "json",
+		dest,
+		"maybe", "n")
+	for _, line := range []string{
+		"choose a number from 1 to",
+		"the layout may only contain r and p",
+		"give 6 rotor sizes",
+		"answer y or n",
+		"Nothing was written.",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("the output does not have %q:\n%s", line, out)
+		}
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("a declined machine was written: %v", err)
+	}
+}
+
+// TestWizardDice checks the wizard with the dice backend reading the script
+// from stdin, as when it is piped, so that the wizard and the dice source
+// share stdin and the answer after the rolls is not lost.
+func TestWizardDice(t *testing.T) {
+	savedSource, savedName := source, sourceName
+	t.Cleanup(func() { source, sourceName = savedSource, savedName })
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dice.json")
+	rolls := strings.Repeat("1 2 3 4 5 6 6 5 4 3 2 1\n", 22)
+	stdin := func(name, script string) {
+		t.Helper()
+		name = filepath.Join(dir, name)
+		if err := os.WriteFile(name, []byte(script), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		os.Stdin = f
+	}
+	saved := os.Stdin
+	t.Cleanup(func() { os.Stdin = saved })
+
+	stdin("script", "dice\n\n\n\n1\n"+dest+"\n"+rolls+"y\n")
+	var out bytes.Buffer
+	if err := runWizard(&wizard{in: os.Stdin, out: &out}); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	got, err := loadProFormaFile(dest, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdin("rolls", rolls)
+	s, err := newSource("dice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeSource(s)
+	want, err := generateMachine(s, engineLayout, rotorSizes, cycleSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.fingerprint() != want.fingerprint() {
+		t.Errorf("the wizard did not write the machine of the dice rolls")
+	}
+}
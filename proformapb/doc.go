@@ -0,0 +1,24 @@
+/*
+Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package proformapb is the gRPC contract of the genProforma service: the
+// protobuf messages mirroring the Rotor, Cycle and Permutator types, and the
+// client and server for the Proforma service (Generate, Verify, Convert and
+// Fingerprint) served by "genProforma grpc".  Clients in other languages can
+// be generated from proforma.proto.
+package proformapb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proforma.proto
@@ -0,0 +1,1166 @@
+// Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.2
+// 	protoc        (unknown)
+// source: proforma.proto
+
+package proformapb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Rotor mirrors the genProforma Rotor.  The rotor bits are stored least
+// significant bit first, followed by a copy of the first 256 bits.
+type Rotor struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Size    int32  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Start   int32  `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	Step    int32  `protobuf:"varint,3,opt,name=step,proto3" json:"step,omitempty"`
+	Current int32  `protobuf:"varint,4,opt,name=current,proto3" json:"current,omitempty"`
+	Rotor   []byte `protobuf:"bytes,5,opt,name=rotor,proto3" json:"rotor,omitempty"`
+}
+
+func (x *Rotor) Reset() {
+	*x = Rotor{}
+	mi := &file_proforma_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rotor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rotor) ProtoMessage() {}
+
+func (x *Rotor) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rotor.ProtoReflect.Descriptor instead.
+func (*Rotor) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Rotor) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Rotor) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Rotor) GetStep() int32 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
+
+func (x *Rotor) GetCurrent() int32 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+func (x *Rotor) GetRotor() []byte {
+	if x != nil {
+		return x.Rotor
+	}
+	return nil
+}
+
+// Cycle mirrors the genProforma Cycle.
+type Cycle struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Start   int32 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	Length  int32 `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+	Current int32 `protobuf:"varint,3,opt,name=current,proto3" json:"current,omitempty"`
+}
+
+func (x *Cycle) Reset() {
+	*x = Cycle{}
+	mi := &file_proforma_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cycle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cycle) ProtoMessage() {}
+
+func (x *Cycle) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cycle.ProtoReflect.Descriptor instead.
+func (*Cycle) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Cycle) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Cycle) GetLength() int32 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *Cycle) GetCurrent() int32 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+// Permutator mirrors the genProforma Permutator.
+type Permutator struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CurrentState  int32    `protobuf:"varint,1,opt,name=current_state,json=currentState,proto3" json:"current_state,omitempty"`
+	MaximalStates int32    `protobuf:"varint,2,opt,name=maximal_states,json=maximalStates,proto3" json:"maximal_states,omitempty"`
+	Cycles        []*Cycle `protobuf:"bytes,3,rep,name=cycles,proto3" json:"cycles,omitempty"`
+	Randp         []byte   `protobuf:"bytes,4,opt,name=randp,proto3" json:"randp,omitempty"`
+}
+
+func (x *Permutator) Reset() {
+	*x = Permutator{}
+	mi := &file_proforma_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Permutator) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Permutator) ProtoMessage() {}
+
+func (x *Permutator) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Permutator.ProtoReflect.Descriptor instead.
+func (*Permutator) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Permutator) GetCurrentState() int32 {
+	if x != nil {
+		return x.CurrentState
+	}
+	return 0
+}
+
+func (x *Permutator) GetMaximalStates() int32 {
+	if x != nil {
+		return x.MaximalStates
+	}
+	return 0
+}
+
+func (x *Permutator) GetCycles() []*Cycle {
+	if x != nil {
+		return x.Cycles
+	}
+	return nil
+}
+
+func (x *Permutator) GetRandp() []byte {
+	if x != nil {
+		return x.Randp
+	}
+	return nil
+}
+
+// Machine is a proforma machine.  The layout gives the order of the rotors
+// ('r') and permutators ('p') in the engine.
+type Machine struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Layout      string        `protobuf:"bytes,1,opt,name=layout,proto3" json:"layout,omitempty"`
+	Rotors      []*Rotor      `protobuf:"bytes,2,rep,name=rotors,proto3" json:"rotors,omitempty"`
+	Permutators []*Permutator `protobuf:"bytes,3,rep,name=permutators,proto3" json:"permutators,omitempty"`
+}
+
+func (x *Machine) Reset() {
+	*x = Machine{}
+	mi := &file_proforma_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Machine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Machine) ProtoMessage() {}
+
+func (x *Machine) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Machine.ProtoReflect.Descriptor instead.
+func (*Machine) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Machine) GetLayout() string {
+	if x != nil {
+		return x.Layout
+	}
+	return ""
+}
+
+func (x *Machine) GetRotors() []*Rotor {
+	if x != nil {
+		return x.Rotors
+	}
+	return nil
+}
+
+func (x *Machine) GetPermutators() []*Permutator {
+	if x != nil {
+		return x.Permutators
+	}
+	return nil
+}
+
+// EncodedMachine is a proforma machine in one of the genProforma input
+// formats ("json" or "ikm").
+type EncodedMachine struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *EncodedMachine) Reset() {
+	*x = EncodedMachine{}
+	mi := &file_proforma_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncodedMachine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodedMachine) ProtoMessage() {}
+
+func (x *EncodedMachine) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodedMachine.ProtoReflect.Descriptor instead.
+func (*EncodedMachine) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EncodedMachine) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *EncodedMachine) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type GenerateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The layout of the machine, "rrprrprr" if empty.
+	Layout string `protobuf:"bytes,1,opt,name=layout,proto3" json:"layout,omitempty"`
+	// The backend used to generate the machine, "random" if empty.
+	Backend string `protobuf:"bytes,2,opt,name=backend,proto3" json:"backend,omitempty"`
+	// The secret, and optional label, used by the keyed backends.
+	Secret string `protobuf:"bytes,3,opt,name=secret,proto3" json:"secret,omitempty"`
+	Label  string `protobuf:"bytes,4,opt,name=label,proto3" json:"label,omitempty"`
+	// If set, the machine is also returned formatted as this output type
+	// ("json", "ikm", "c", "rust" or "python").
+	OutputType string `protobuf:"bytes,5,opt,name=output_type,json=outputType,proto3" json:"output_type,omitempty"`
+}
+
+func (x *GenerateRequest) Reset() {
+	*x = GenerateRequest{}
+	mi := &file_proforma_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRequest) ProtoMessage() {}
+
+func (x *GenerateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRequest) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GenerateRequest) GetLayout() string {
+	if x != nil {
+		return x.Layout
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetOutputType() string {
+	if x != nil {
+		return x.OutputType
+	}
+	return ""
+}
+
+type GenerateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Machine     *Machine `protobuf:"bytes,1,opt,name=machine,proto3" json:"machine,omitempty"`
+	Formatted   []byte   `protobuf:"bytes,2,opt,name=formatted,proto3" json:"formatted,omitempty"`
+	Backend     string   `protobuf:"bytes,3,opt,name=backend,proto3" json:"backend,omitempty"`
+	Fingerprint string   `protobuf:"bytes,4,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	Sha256      string   `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
+}
+
+func (x *GenerateResponse) Reset() {
+	*x = GenerateResponse{}
+	mi := &file_proforma_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateResponse) ProtoMessage() {}
+
+func (x *GenerateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateResponse.ProtoReflect.Descriptor instead.
+func (*GenerateResponse) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GenerateResponse) GetMachine() *Machine {
+	if x != nil {
+		return x.Machine
+	}
+	return nil
+}
+
+func (x *GenerateResponse) GetFormatted() []byte {
+	if x != nil {
+		return x.Formatted
+	}
+	return nil
+}
+
+func (x *GenerateResponse) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *GenerateResponse) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *GenerateResponse) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type VerifyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Input:
+	//	*VerifyRequest_Machine
+	//	*VerifyRequest_Encoded
+	Input isVerifyRequest_Input `protobuf_oneof:"input"`
+}
+
+func (x *VerifyRequest) Reset() {
+	*x = VerifyRequest{}
+	mi := &file_proforma_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRequest) ProtoMessage() {}
+
+func (x *VerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{7}
+}
+
+func (m *VerifyRequest) GetInput() isVerifyRequest_Input {
+	if m != nil {
+		return m.Input
+	}
+	return nil
+}
+
+func (x *VerifyRequest) GetMachine() *Machine {
+	if x, ok := x.GetInput().(*VerifyRequest_Machine); ok {
+		return x.Machine
+	}
+	return nil
+}
+
+func (x *VerifyRequest) GetEncoded() *EncodedMachine {
+	if x, ok := x.GetInput().(*VerifyRequest_Encoded); ok {
+		return x.Encoded
+	}
+	return nil
+}
+
+type isVerifyRequest_Input interface {
+	isVerifyRequest_Input()
+}
+
+type VerifyRequest_Machine struct {
+	Machine *Machine `protobuf:"bytes,1,opt,name=machine,proto3,oneof"`
+}
+
+type VerifyRequest_Encoded struct {
+	Encoded *EncodedMachine `protobuf:"bytes,2,opt,name=encoded,proto3,oneof"`
+}
+
+func (*VerifyRequest_Machine) isVerifyRequest_Input() {}
+
+func (*VerifyRequest_Encoded) isVerifyRequest_Input() {}
+
+type VerifyResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Valid       bool     `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	Errors      []string `protobuf:"bytes,2,rep,name=errors,proto3" json:"errors,omitempty"`
+	Layout      string   `protobuf:"bytes,3,opt,name=layout,proto3" json:"layout,omitempty"`
+	Fingerprint string   `protobuf:"bytes,4,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	Sha256      string   `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
+}
+
+func (x *VerifyResponse) Reset() {
+	*x = VerifyResponse{}
+	mi := &file_proforma_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyResponse) ProtoMessage() {}
+
+func (x *VerifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyResponse.ProtoReflect.Descriptor instead.
+func (*VerifyResponse) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *VerifyResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *VerifyResponse) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetLayout() string {
+	if x != nil {
+		return x.Layout
+	}
+	return ""
+}
+
+func (x *VerifyResponse) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *VerifyResponse) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type ConvertRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Input:
+	//	*ConvertRequest_Machine
+	//	*ConvertRequest_Encoded
+	Input      isConvertRequest_Input `protobuf_oneof:"input"`
+	OutputType string                 `protobuf:"bytes,3,opt,name=output_type,json=outputType,proto3" json:"output_type,omitempty"`
+}
+
+func (x *ConvertRequest) Reset() {
+	*x = ConvertRequest{}
+	mi := &file_proforma_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConvertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConvertRequest) ProtoMessage() {}
+
+func (x *ConvertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConvertRequest.ProtoReflect.Descriptor instead.
+func (*ConvertRequest) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{9}
+}
+
+func (m *ConvertRequest) GetInput() isConvertRequest_Input {
+	if m != nil {
+		return m.Input
+	}
+	return nil
+}
+
+func (x *ConvertRequest) GetMachine() *Machine {
+	if x, ok := x.GetInput().(*ConvertRequest_Machine); ok {
+		return x.Machine
+	}
+	return nil
+}
+
+func (x *ConvertRequest) GetEncoded() *EncodedMachine {
+	if x, ok := x.GetInput().(*ConvertRequest_Encoded); ok {
+		return x.Encoded
+	}
+	return nil
+}
+
+func (x *ConvertRequest) GetOutputType() string {
+	if x != nil {
+		return x.OutputType
+	}
+	return ""
+}
+
+type isConvertRequest_Input interface {
+	isConvertRequest_Input()
+}
+
+type ConvertRequest_Machine struct {
+	Machine *Machine `protobuf:"bytes,1,opt,name=machine,proto3,oneof"`
+}
+
+type ConvertRequest_Encoded struct {
+	Encoded *EncodedMachine `protobuf:"bytes,2,opt,name=encoded,proto3,oneof"`
+}
+
+func (*ConvertRequest_Machine) isConvertRequest_Input() {}
+
+func (*ConvertRequest_Encoded) isConvertRequest_Input() {}
+
+type ConvertResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *ConvertResponse) Reset() {
+	*x = ConvertResponse{}
+	mi := &file_proforma_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConvertResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConvertResponse) ProtoMessage() {}
+
+func (x *ConvertResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConvertResponse.ProtoReflect.Descriptor instead.
+func (*ConvertResponse) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ConvertResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type FingerprintRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Input:
+	//	*FingerprintRequest_Machine
+	//	*FingerprintRequest_Encoded
+	Input isFingerprintRequest_Input `protobuf_oneof:"input"`
+}
+
+func (x *FingerprintRequest) Reset() {
+	*x = FingerprintRequest{}
+	mi := &file_proforma_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FingerprintRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FingerprintRequest) ProtoMessage() {}
+
+func (x *FingerprintRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FingerprintRequest.ProtoReflect.Descriptor instead.
+func (*FingerprintRequest) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{11}
+}
+
+func (m *FingerprintRequest) GetInput() isFingerprintRequest_Input {
+	if m != nil {
+		return m.Input
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetMachine() *Machine {
+	if x, ok := x.GetInput().(*FingerprintRequest_Machine); ok {
+		return x.Machine
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetEncoded() *EncodedMachine {
+	if x, ok := x.GetInput().(*FingerprintRequest_Encoded); ok {
+		return x.Encoded
+	}
+	return nil
+}
+
+type isFingerprintRequest_Input interface {
+	isFingerprintRequest_Input()
+}
+
+type FingerprintRequest_Machine struct {
+	Machine *Machine `protobuf:"bytes,1,opt,name=machine,proto3,oneof"`
+}
+
+type FingerprintRequest_Encoded struct {
+	Encoded *EncodedMachine `protobuf:"bytes,2,opt,name=encoded,proto3,oneof"`
+}
+
+func (*FingerprintRequest_Machine) isFingerprintRequest_Input() {}
+
+func (*FingerprintRequest_Encoded) isFingerprintRequest_Input() {}
+
+type FingerprintResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Fingerprint string `protobuf:"bytes,1,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	Sha256      string `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"`
+}
+
+func (x *FingerprintResponse) Reset() {
+	*x = FingerprintResponse{}
+	mi := &file_proforma_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FingerprintResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FingerprintResponse) ProtoMessage() {}
+
+func (x *FingerprintResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proforma_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FingerprintResponse.ProtoReflect.Descriptor instead.
+func (*FingerprintResponse) Descriptor() ([]byte, []int) {
+	return file_proforma_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *FingerprintResponse) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+var File_proforma_proto protoreflect.FileDescriptor
+
+var file_proforma_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x0e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31,
+	0x22, 0x75, 0x0a, 0x05, 0x52, 0x6f, 0x74, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x73, 0x74,
+	0x61, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x65, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x04, 0x73, 0x74, 0x65, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x75, 0x72, 0x72, 0x65,
+	0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x74, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x05, 0x72, 0x6f, 0x74, 0x6f, 0x72, 0x22, 0x4f, 0x0a, 0x05, 0x43, 0x79, 0x63, 0x6c, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x07, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x22, 0x9d, 0x01, 0x0a, 0x0a, 0x50, 0x65, 0x72,
+	0x6d, 0x75, 0x74, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x23, 0x0a, 0x0d, 0x63, 0x75, 0x72, 0x72, 0x65,
+	0x6e, 0x74, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c,
+	0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x25, 0x0a, 0x0e,
+	0x6d, 0x61, 0x78, 0x69, 0x6d, 0x61, 0x6c, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x6d, 0x61, 0x78, 0x69, 0x6d, 0x61, 0x6c, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x73, 0x12, 0x2d, 0x0a, 0x06, 0x63, 0x79, 0x63, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d,
+	0x61, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x79, 0x63, 0x6c, 0x65, 0x52, 0x06, 0x63, 0x79, 0x63, 0x6c,
+	0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x61, 0x6e, 0x64, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x05, 0x72, 0x61, 0x6e, 0x64, 0x70, 0x22, 0x8e, 0x01, 0x0a, 0x07, 0x4d, 0x61, 0x63,
+	0x68, 0x69, 0x6e, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x61, 0x79, 0x6f, 0x75, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6c, 0x61, 0x79, 0x6f, 0x75, 0x74, 0x12, 0x2d, 0x0a, 0x06,
+	0x72, 0x6f, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x67,
+	0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f,
+	0x74, 0x6f, 0x72, 0x52, 0x06, 0x72, 0x6f, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x3c, 0x0a, 0x0b, 0x70,
+	0x65, 0x72, 0x6d, 0x75, 0x74, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x1a, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76,
+	0x31, 0x2e, 0x50, 0x65, 0x72, 0x6d, 0x75, 0x74, 0x61, 0x74, 0x6f, 0x72, 0x52, 0x0b, 0x70, 0x65,
+	0x72, 0x6d, 0x75, 0x74, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x22, 0x38, 0x0a, 0x0e, 0x45, 0x6e, 0x63,
+	0x6f, 0x64, 0x65, 0x64, 0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64,
+	0x61, 0x74, 0x61, 0x22, 0x92, 0x01, 0x0a, 0x0f, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x61, 0x79, 0x6f, 0x75,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6c, 0x61, 0x79, 0x6f, 0x75, 0x74, 0x12,
+	0x18, 0x0a, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x63,
+	0x72, 0x65, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x75, 0x74, 0x70, 0x75,
+	0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6f, 0x75,
+	0x74, 0x70, 0x75, 0x74, 0x54, 0x79, 0x70, 0x65, 0x22, 0xb7, 0x01, 0x0a, 0x10, 0x47, 0x65, 0x6e,
+	0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a,
+	0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17,
+	0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e,
+	0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x52, 0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65,
+	0x12, 0x1c, 0x0a, 0x09, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x09, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x74, 0x65, 0x64, 0x12, 0x18,
+	0x0a, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x67,
+	0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x66,
+	0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68,
+	0x61, 0x32, 0x35, 0x36, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x68, 0x61, 0x32,
+	0x35, 0x36, 0x22, 0x89, 0x01, 0x0a, 0x0d, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x33, 0x0a, 0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f,
+	0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x48, 0x00,
+	0x52, 0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x12, 0x3a, 0x0a, 0x07, 0x65, 0x6e, 0x63,
+	0x6f, 0x64, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x67, 0x65, 0x6e,
+	0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x63, 0x6f,
+	0x64, 0x65, 0x64, 0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x48, 0x00, 0x52, 0x07, 0x65, 0x6e,
+	0x63, 0x6f, 0x64, 0x65, 0x64, 0x42, 0x07, 0x0a, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x22, 0x90,
+	0x01, 0x0a, 0x0e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x12,
+	0x16, 0x0a, 0x06, 0x6c, 0x61, 0x79, 0x6f, 0x75, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x6c, 0x61, 0x79, 0x6f, 0x75, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x67, 0x65,
+	0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x66, 0x69,
+	0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61,
+	0x32, 0x35, 0x36, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35,
+	0x36, 0x22, 0xab, 0x01, 0x0a, 0x0e, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x33, 0x0a, 0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f,
+	0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x48, 0x00,
+	0x52, 0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x12, 0x3a, 0x0a, 0x07, 0x65, 0x6e, 0x63,
+	0x6f, 0x64, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x67, 0x65, 0x6e,
+	0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x63, 0x6f,
+	0x64, 0x65, 0x64, 0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x48, 0x00, 0x52, 0x07, 0x65, 0x6e,
+	0x63, 0x6f, 0x64, 0x65, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f,
+	0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6f, 0x75, 0x74, 0x70,
+	0x75, 0x74, 0x54, 0x79, 0x70, 0x65, 0x42, 0x07, 0x0a, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x22,
+	0x25, 0x0a, 0x0f, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x8e, 0x01, 0x0a, 0x12, 0x46, 0x69, 0x6e, 0x67, 0x65,
+	0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x33, 0x0a,
+	0x07, 0x6d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17,
+	0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e,
+	0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x48, 0x00, 0x52, 0x07, 0x6d, 0x61, 0x63, 0x68, 0x69,
+	0x6e, 0x65, 0x12, 0x3a, 0x0a, 0x07, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d,
+	0x61, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x64, 0x4d, 0x61, 0x63, 0x68,
+	0x69, 0x6e, 0x65, 0x48, 0x00, 0x52, 0x07, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x64, 0x42, 0x07,
+	0x0a, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x22, 0x4f, 0x0a, 0x13, 0x46, 0x69, 0x6e, 0x67, 0x65,
+	0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x20,
+	0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74,
+	0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x32, 0xc6, 0x02, 0x0a, 0x08, 0x50, 0x72, 0x6f,
+	0x66, 0x6f, 0x72, 0x6d, 0x61, 0x12, 0x4d, 0x0a, 0x08, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74,
+	0x65, 0x12, 0x1f, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e,
+	0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x20, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x47, 0x0a, 0x06, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x12, 0x1d,
+	0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e,
+	0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e,
+	0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x56,
+	0x65, 0x72, 0x69, 0x66, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4a, 0x0a,
+	0x07, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x12, 0x1e, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72,
+	0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72,
+	0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x56, 0x0a, 0x0b, 0x46, 0x69, 0x6e,
+	0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x12, 0x22, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72,
+	0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69, 0x6e, 0x67, 0x65, 0x72,
+	0x70, 0x72, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x67,
+	0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69,
+	0x6e, 0x67, 0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x42, 0x50, 0x0a, 0x21, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x62, 0x67, 0x61, 0x6c, 0x6c, 0x69, 0x65, 0x2e, 0x67, 0x65, 0x6e, 0x70, 0x72, 0x6f, 0x66, 0x6f,
+	0x72, 0x6d, 0x61, 0x2e, 0x76, 0x31, 0x50, 0x01, 0x5a, 0x29, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x67, 0x61, 0x6c, 0x6c, 0x69, 0x65, 0x2f, 0x67, 0x65, 0x6e,
+	0x50, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x2f, 0x70, 0x72, 0x6f, 0x66, 0x6f, 0x72, 0x6d,
+	0x61, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_proforma_proto_rawDescOnce sync.Once
+	file_proforma_proto_rawDescData = file_proforma_proto_rawDesc
+)
+
+func file_proforma_proto_rawDescGZIP() []byte {
+	file_proforma_proto_rawDescOnce.Do(func() {
+		file_proforma_proto_rawDescData = protoimpl.X.CompressGZIP(file_proforma_proto_rawDescData)
+	})
+	return file_proforma_proto_rawDescData
+}
+
+var file_proforma_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_proforma_proto_goTypes = []any{
+	(*Rotor)(nil),               // 0: genproforma.v1.Rotor
+	(*Cycle)(nil),               // 1: genproforma.v1.Cycle
+	(*Permutator)(nil),          // 2: genproforma.v1.Permutator
+	(*Machine)(nil),             // 3: genproforma.v1.Machine
+	(*EncodedMachine)(nil),      // 4: genproforma.v1.EncodedMachine
+	(*GenerateRequest)(nil),     // 5: genproforma.v1.GenerateRequest
+	(*GenerateResponse)(nil),    // 6: genproforma.v1.GenerateResponse
+	(*VerifyRequest)(nil),       // 7: genproforma.v1.VerifyRequest
+	(*VerifyResponse)(nil),      // 8: genproforma.v1.VerifyResponse
+	(*ConvertRequest)(nil),      // 9: genproforma.v1.ConvertRequest
+	(*ConvertResponse)(nil),     // 10: genproforma.v1.ConvertResponse
+	(*FingerprintRequest)(nil),  // 11: genproforma.v1.FingerprintRequest
+	(*FingerprintResponse)(nil), // 12: genproforma.v1.FingerprintResponse
+}
+var file_proforma_proto_depIdxs = []int32{
+	1,  // 0: genproforma.v1.Permutator.cycles:type_name -> genproforma.v1.Cycle
+	0,  // 1: genproforma.v1.Machine.rotors:type_name -> genproforma.v1.Rotor
+	2,  // 2: genproforma.v1.Machine.permutators:type_name -> genproforma.v1.Permutator
+	3,  // 3: genproforma.v1.GenerateResponse.machine:type_name -> genproforma.v1.Machine
+	3,  // 4: genproforma.v1.VerifyRequest.machine:type_name -> genproforma.v1.Machine
+	4,  // 5: genproforma.v1.VerifyRequest.encoded:type_name -> genproforma.v1.EncodedMachine
+	3,  // 6: genproforma.v1.ConvertRequest.machine:type_name -> genproforma.v1.Machine
+	4,  // 7: genproforma.v1.ConvertRequest.encoded:type_name -> genproforma.v1.EncodedMachine
+	3,  // 8: genproforma.v1.FingerprintRequest.machine:type_name -> genproforma.v1.Machine
+	4,  // 9: genproforma.v1.FingerprintRequest.encoded:type_name -> genproforma.v1.EncodedMachine
+	5,  // 10: genproforma.v1.Proforma.Generate:input_type -> genproforma.v1.GenerateRequest
+	7,  // 11: genproforma.v1.Proforma.Verify:input_type -> genproforma.v1.VerifyRequest
+	9,  // 12: genproforma.v1.Proforma.Convert:input_type -> genproforma.v1.ConvertRequest
+	11, // 13: genproforma.v1.Proforma.Fingerprint:input_type -> genproforma.v1.FingerprintRequest
+	6,  // 14: genproforma.v1.Proforma.Generate:output_type -> genproforma.v1.GenerateResponse
+	8,  // 15: genproforma.v1.Proforma.Verify:output_type -> genproforma.v1.VerifyResponse
+	10, // 16: genproforma.v1.Proforma.Convert:output_type -> genproforma.v1.ConvertResponse
+	12, // 17: genproforma.v1.Proforma.Fingerprint:output_type -> genproforma.v1.FingerprintResponse
+	14, // [14:18] is the sub-list for method output_type
+	10, // [10:14] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_proforma_proto_init() }
+func file_proforma_proto_init() {
+	if File_proforma_proto != nil {
+		return
+	}
+	file_proforma_proto_msgTypes[7].OneofWrappers = []any{
+		(*VerifyRequest_Machine)(nil),
+		(*VerifyRequest_Encoded)(nil),
+	}
+	file_proforma_proto_msgTypes[9].OneofWrappers = []any{
+		(*ConvertRequest_Machine)(nil),
+		(*ConvertRequest_Encoded)(nil),
+	}
+	file_proforma_proto_msgTypes[11].OneofWrappers = []any{
+		(*FingerprintRequest_Machine)(nil),
+		(*FingerprintRequest_Encoded)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proforma_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proforma_proto_goTypes,
+		DependencyIndexes: file_proforma_proto_depIdxs,
+		MessageInfos:      file_proforma_proto_msgTypes,
+	}.Build()
+	File_proforma_proto = out.File
+	file_proforma_proto_rawDesc = nil
+	file_proforma_proto_goTypes = nil
+	file_proforma_proto_depIdxs = nil
+}
@@ -0,0 +1,261 @@
+// Copyright © 2021 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proforma.proto
+
+package proformapb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Proforma_Generate_FullMethodName    = "/genproforma.v1.Proforma/Generate"
+	Proforma_Verify_FullMethodName      = "/genproforma.v1.Proforma/Verify"
+	Proforma_Convert_FullMethodName     = "/genproforma.v1.Proforma/Convert"
+	Proforma_Fingerprint_FullMethodName = "/genproforma.v1.Proforma/Fingerprint"
+)
+
+// ProformaClient is the client API for Proforma service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Proforma generates, verifies and converts proforma machines.
+type ProformaClient interface {
+	// Generate generates a new proforma machine.
+	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error)
+	// Verify checks that a proforma machine is valid.
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+	// Convert formats a proforma machine as one of the output types.
+	Convert(ctx context.Context, in *ConvertRequest, opts ...grpc.CallOption) (*ConvertResponse, error)
+	// Fingerprint returns the fingerprint of a proforma machine.
+	Fingerprint(ctx context.Context, in *FingerprintRequest, opts ...grpc.CallOption) (*FingerprintResponse, error)
+}
+
+type proformaClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProformaClient(cc grpc.ClientConnInterface) ProformaClient {
+	return &proformaClient{cc}
+}
+
+func (c *proformaClient) Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateResponse)
+	err := c.cc.Invoke(ctx, Proforma_Generate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proformaClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VerifyResponse)
+	err := c.cc.Invoke(ctx, Proforma_Verify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proformaClient) Convert(ctx context.Context, in *ConvertRequest, opts ...grpc.CallOption) (*ConvertResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConvertResponse)
+	err := c.cc.Invoke(ctx, Proforma_Convert_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proformaClient) Fingerprint(ctx context.Context, in *FingerprintRequest, opts ...grpc.CallOption) (*FingerprintResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FingerprintResponse)
+	err := c.cc.Invoke(ctx, Proforma_Fingerprint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProformaServer is the server API for Proforma service.
+// All implementations must embed UnimplementedProformaServer
+// for forward compatibility.
+//
+// Proforma generates, verifies and converts proforma machines.
+type ProformaServer interface {
+	// Generate generates a new proforma machine.
+	Generate(context.Context, *GenerateRequest) (*GenerateResponse, error)
+	// Verify checks that a proforma machine is valid.
+	Verify(context.Context, *VerifyRequest) (*VerifyResponse, error)
+	// Convert formats a proforma machine as one of the output types.
+	Convert(context.Context, *ConvertRequest) (*ConvertResponse, error)
+	// Fingerprint returns the fingerprint of a proforma machine.
+	Fingerprint(context.Context, *FingerprintRequest) (*FingerprintResponse, error)
+	mustEmbedUnimplementedProformaServer()
+}
+
+// UnimplementedProformaServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedProformaServer struct{}
+
+func (UnimplementedProformaServer) Generate(context.Context, *GenerateRequest) (*GenerateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+func (UnimplementedProformaServer) Verify(context.Context, *VerifyRequest) (*VerifyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Verify not implemented")
+}
+func (UnimplementedProformaServer) Convert(context.Context, *ConvertRequest) (*ConvertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Convert not implemented")
+}
+func (UnimplementedProformaServer) Fingerprint(context.Context, *FingerprintRequest) (*FingerprintResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Fingerprint not implemented")
+}
+func (UnimplementedProformaServer) mustEmbedUnimplementedProformaServer() {}
+func (UnimplementedProformaServer) testEmbeddedByValue()                  {}
+
+// UnsafeProformaServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProformaServer will
+// result in compilation errors.
+type UnsafeProformaServer interface {
+	mustEmbedUnimplementedProformaServer()
+}
+
+func RegisterProformaServer(s grpc.ServiceRegistrar, srv ProformaServer) {
+	// If the following call pancis, it indicates UnimplementedProformaServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Proforma_ServiceDesc, srv)
+}
+
+func _Proforma_Generate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProformaServer).Generate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Proforma_Generate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProformaServer).Generate(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proforma_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProformaServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Proforma_Verify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProformaServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proforma_Convert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConvertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProformaServer).Convert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Proforma_Convert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProformaServer).Convert(ctx, req.(*ConvertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Proforma_Fingerprint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FingerprintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProformaServer).Fingerprint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Proforma_Fingerprint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProformaServer).Fingerprint(ctx, req.(*FingerprintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Proforma_ServiceDesc is the grpc.ServiceDesc for Proforma service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Proforma_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "genproforma.v1.Proforma",
+	HandlerType: (*ProformaServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Generate",
+			Handler:    _Proforma_Generate_Handler,
+		},
+		{
+			MethodName: "Verify",
+			Handler:    _Proforma_Verify_Handler,
+		},
+		{
+			MethodName: "Convert",
+			Handler:    _Proforma_Convert_Handler,
+		},
+		{
+			MethodName: "Fingerprint",
+			Handler:    _Proforma_Fingerprint_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proforma.proto",
+}